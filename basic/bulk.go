@@ -0,0 +1,204 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// bulkCall is one key of a bulk load in flight
+type bulkCall[V any] struct {
+	done chan struct{}
+	obj  V
+	ok   bool
+	err  error
+}
+
+// BulkLoader run the bulk loader of GetMany. Keys are split into batches
+// of at most batch keys that are loaded concurrently, and a key that is
+// already being loaded by another GetMany waits for that load instead of
+// being loaded again. Keys are not shared with the callback of Get: a key
+// that Get is loading at the same time may be loaded by both.
+type BulkLoader[K comparable, V any] struct {
+	load  func(context.Context, []K) (map[K]V, error)
+	store func(K, V)
+	batch int
+	mu    sync.Mutex
+	calls map[K]*bulkCall[V]
+}
+
+// NewBulkLoader new loader calling load with batches of at most batch keys,
+// store puts every loaded value in the cache before the waiters see it
+func NewBulkLoader[K comparable, V any](load func(context.Context, []K) (map[K]V, error), batch int, store func(K, V)) *BulkLoader[K, V] {
+	if batch <= 0 {
+		batch = 100
+	}
+	return &BulkLoader[K, V]{
+		load:  load,
+		store: store,
+		batch: batch,
+		calls: make(map[K]*bulkCall[V]),
+	}
+}
+
+// Load return the values of ks the loader found. The error is the first
+// one of the batches, or of ctx when it is done before a shared load is;
+// the values loaded by then are returned with it.
+func (l *BulkLoader[K, V]) Load(ctx context.Context, ks []K) (map[K]V, error) {
+	var own []K
+	wait := make(map[K]*bulkCall[V], len(ks))
+	l.mu.Lock()
+	for _, k := range ks {
+		if _, ok := wait[k]; ok {
+			continue
+		}
+		call, ok := l.calls[k]
+		if !ok {
+			call = &bulkCall[V]{done: make(chan struct{})}
+			l.calls[k] = call
+			own = append(own, k)
+		}
+		wait[k] = call
+	}
+	l.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for len(own) > 0 {
+		n := l.batch
+		if n > len(own) {
+			n = len(own)
+		}
+		wg.Add(1)
+		go func(batch []K) {
+			defer wg.Done()
+			l.run(ctx, batch, wait)
+		}(own[:n])
+		own = own[n:]
+	}
+	wg.Wait()
+
+	r := make(map[K]V, len(wait))
+	var first error
+	pending := false
+	for k, call := range wait {
+		if !call.wait(ctx) {
+			pending = true
+			continue
+		}
+		if call.ok {
+			r[k] = call.obj
+		} else if call.err != nil && first == nil {
+			first = call.err
+		}
+	}
+	if pending {
+		return r, ctx.Err()
+	}
+	return r, first
+}
+
+// wait for the load of call unless ctx is done first, a finished load
+// always wins over ctx
+func (call *bulkCall[V]) wait(ctx context.Context) bool {
+	select {
+	case <-call.done:
+		return true
+	default:
+	}
+	select {
+	case <-call.done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// run load one batch and hand the result to every waiter. A panic of the
+// loader is the error of the batch, the waiters are released either way.
+func (l *BulkLoader[K, V]) run(ctx context.Context, batch []K, calls map[K]*bulkCall[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("bulk loader panic: %v", r)
+			for _, k := range batch {
+				calls[k].ok, calls[k].err = false, err
+			}
+		}
+		l.mu.Lock()
+		for _, k := range batch {
+			delete(l.calls, k)
+		}
+		l.mu.Unlock()
+		for _, k := range batch {
+			close(calls[k].done)
+		}
+	}()
+	found, err := l.load(ctx, batch)
+	for _, k := range batch {
+		call := calls[k]
+		call.obj, call.ok = found[k]
+		if call.ok {
+			l.store(k, call.obj)
+		} else {
+			call.err = err
+		}
+	}
+}
+
+// WithBulkLoader set the loader GetMany hands its misses to
+func (c *PartitionCache[K, V]) WithBulkLoader(call func(context.Context, []K) (map[K]V, error)) {
+	c.bulk = NewBulkLoader(call, c.loadBatch, func(k K, v V) {
+		c.SetWithExp(k, v, c.defaultDuration)
+	})
+}
+
+// GetMany return the cached values of ks and load the rest: all at once
+// with the bulk loader, or one by one with the callback when there is no
+// bulk loader. Expired entries are loaded again. Keys that are neither
+// cached nor loaded are left out of the result. A key Get is loading at
+// the same time may be loaded again by the bulk loader.
+func (c *PartitionCache[K, V]) GetMany(ctx context.Context, ks []K) (map[K]V, error) {
+	r := make(map[K]V, len(ks))
+	var misses []K
+	for _, k := range ks {
+		h := ehash(k)
+		if v, ok := c.table.Load().bucket(h).hit(k, h); ok {
+			r[k] = v
+		} else {
+			misses = append(misses, k)
+		}
+	}
+	if len(misses) == 0 {
+		return r, nil
+	}
+	if c.bulk == nil {
+		for _, k := range misses {
+			if err := ctx.Err(); err != nil {
+				return r, err
+			}
+			if v, err := c.Get(k); err == nil {
+				r[k] = v
+			}
+		}
+		return r, nil
+	}
+	loaded, err := c.bulk.Load(ctx, misses)
+	for k, v := range loaded {
+		r[k] = v
+	}
+	return r, err
+}
+
+// hit return the value of k when it is cached and not expired
+func (b *bucket[K, V]) hit(k K, h uintptr) (r V, ok bool) {
+	b = b.rlock(h)
+	item, ok := b.items[h]
+	b.mu.RUnlock()
+	if !ok || !item.owned(k, nil, b.stamps.epoch.Load()) || item.expiredAt(b.now()) {
+		return r, false
+	}
+	item.hits.Add(1)
+	if item.Disuse() {
+		b.use(k, h)
+	}
+	return item.obj, true
+}
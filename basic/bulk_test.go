@@ -0,0 +1,150 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBulkLoader(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("misses are loaded in batches and cached", t, func() {
+		var mu sync.Mutex
+		var batches [][]int
+		cache := NewPartitionCache[int, int](WithLoadBatch(3))
+		defer cache.Close()
+		cache.WithBulkLoader(func(ctx context.Context, ks []int) (map[int]int, error) {
+			mu.Lock()
+			batches = append(batches, append([]int(nil), ks...))
+			mu.Unlock()
+			r := make(map[int]int)
+			for _, k := range ks {
+				if k != 7 {
+					r[k] = k * 10
+				}
+			}
+			return r, nil
+		})
+		cache.Set(1, 100)
+		r, err := cache.GetMany(ctx, []int{1, 2, 3, 4, 5, 6, 7, 2})
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, map[int]int{1: 100, 2: 20, 3: 30, 4: 40, 5: 50, 6: 60})
+		So(batches, ShouldHaveLength, 2)
+		var loaded []int
+		for _, b := range batches {
+			So(len(b), ShouldBeLessThanOrEqualTo, 3)
+			loaded = append(loaded, b...)
+		}
+		sort.Ints(loaded)
+		So(loaded, ShouldResemble, []int{2, 3, 4, 5, 6, 7})
+		v, err := cache.Get(4)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 40)
+	})
+
+	Convey("a key in flight is loaded once", t, func() {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l := NewBulkLoader(func(ctx context.Context, ks []string) (map[string]int, error) {
+			calls.Add(1)
+			<-release
+			r := make(map[string]int)
+			for _, k := range ks {
+				r[k] = len(k)
+			}
+			return r, nil
+		}, 10, func(string, int) {})
+		var wg sync.WaitGroup
+		results := make([]map[string]int, 2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[0], _ = l.Load(ctx, []string{"a", "bb"})
+		}()
+		for {
+			l.mu.Lock()
+			n := len(l.calls)
+			l.mu.Unlock()
+			if n == 2 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[1], _ = l.Load(ctx, []string{"bb"})
+		}()
+		time.Sleep(5 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		So(calls.Load(), ShouldEqual, 1)
+		So(results[0], ShouldResemble, map[string]int{"a": 1, "bb": 2})
+		So(results[1], ShouldResemble, map[string]int{"bb": 2})
+	})
+
+	Convey("the loader error is reported with what was found", t, func() {
+		boom := errors.New("boom")
+		cache := NewPartitionCache[int, int](WithLoadBatch(1))
+		defer cache.Close()
+		cache.WithBulkLoader(func(ctx context.Context, ks []int) (map[int]int, error) {
+			if ks[0] == 2 {
+				return nil, boom
+			}
+			return map[int]int{ks[0]: ks[0]}, nil
+		})
+		r, err := cache.GetMany(ctx, []int{1, 2})
+		So(err, ShouldEqual, boom)
+		So(r, ShouldResemble, map[int]int{1: 1})
+	})
+
+	Convey("values loaded before ctx is done are kept", t, func() {
+		l := NewBulkLoader(func(_ context.Context, ks []int) (map[int]int, error) {
+			r := make(map[int]int)
+			for _, k := range ks {
+				r[k] = k
+			}
+			return r, nil
+		}, 2, func(int, int) {})
+		done, cancel := context.WithCancel(ctx)
+		cancel()
+		for i := 0; i < 20; i++ {
+			r, err := l.Load(done, []int{1, 2, 3, 4, 5, 6})
+			So(err, ShouldBeNil)
+			So(r, ShouldHaveLength, 6)
+		}
+	})
+
+	Convey("a panic of the loader is an error and releases the waiters", t, func() {
+		calls := atomic.Int32{}
+		l := NewBulkLoader(func(_ context.Context, ks []int) (map[int]int, error) {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+			return map[int]int{1: 1}, nil
+		}, 10, func(int, int) {})
+		_, err := l.Load(ctx, []int{1, 2})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "boom")
+		So(l.calls, ShouldBeEmpty)
+		r, err := l.Load(ctx, []int{1})
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, map[int]int{1: 1})
+	})
+
+	Convey("without a bulk loader the callback loads one key at a time", t, func() {
+		cache := NewPartitionCache[int, int]()
+		defer cache.Close()
+		cache.WithCallback(func(k int) (int, error) { return -k, nil })
+		r, err := cache.GetMany(ctx, []int{1, 2})
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, map[int]int{1: -1, 2: -2})
+	})
+}
@@ -0,0 +1,52 @@
+package basic
+
+// clockRing is the ring of the CLOCK (second chance) policy. Entries
+// remember their slot, a hit colors them white, the hand demotes white
+// entries to black and stops at the first black one: that is the victim.
+type clockRing[K comparable] struct {
+	keys []K
+	hand int
+}
+
+func (r *clockRing[K]) len() int {
+	return len(r.keys)
+}
+
+// add place k in a new slot and return it
+func (r *clockRing[K]) add(k K) int {
+	r.keys = append(r.keys, k)
+	return len(r.keys) - 1
+}
+
+// set put k in slot, normally the victim slot returned by sweep
+func (r *clockRing[K]) set(slot int, k K) {
+	r.keys[slot] = k
+}
+
+// sweep advance the hand until demote reports an entry that is not worth
+// keeping, and return its slot. demote turns a white entry black and
+// reports true, so the ring never has to go around more than once.
+func (r *clockRing[K]) sweep(demote func(K) bool) int {
+	for {
+		if r.hand >= len(r.keys) {
+			r.hand = 0
+		}
+		slot := r.hand
+		r.hand++
+		if !demote(r.keys[slot]) {
+			return slot
+		}
+	}
+}
+
+// remove free slot by moving the last key into it. It returns that key,
+// whose owner has to remember the new slot, unless slot was the last one.
+func (r *clockRing[K]) remove(slot int) (K, bool) {
+	last := len(r.keys) - 1
+	moved := r.keys[last]
+	r.keys[slot] = moved
+	var zero K
+	r.keys[last] = zero
+	r.keys = r.keys[:last]
+	return moved, slot != last
+}
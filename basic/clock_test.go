@@ -0,0 +1,49 @@
+package basic
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClockRing(t *testing.T) {
+	Convey("the hand demotes white entries and stops at black", t, func() {
+		var r clockRing[string]
+		color := map[string]Color{}
+		for _, k := range []string{"a", "b", "c"} {
+			r.add(k)
+			color[k] = black
+		}
+		color["a"] = white
+		color["c"] = white
+		demote := func(k string) bool {
+			if color[k] == black {
+				return false
+			}
+			color[k] = black
+			return true
+		}
+		So(r.keys[r.sweep(demote)], ShouldEqual, "b")
+		So(color["a"], ShouldEqual, black)
+		r.set(1, "d")
+		color["d"] = white
+		// c is demoted, the hand wraps around to a which is black by now
+		So(r.keys[r.sweep(demote)], ShouldEqual, "a")
+		So(color["c"], ShouldEqual, black)
+		So(color["d"], ShouldEqual, white)
+	})
+
+	Convey("remove fills the slot with the last key", t, func() {
+		var r clockRing[string]
+		for _, k := range []string{"a", "b", "c"} {
+			r.add(k)
+		}
+		moved, ok := r.remove(0)
+		So(ok, ShouldBeTrue)
+		So(moved, ShouldEqual, "c")
+		So(r.keys, ShouldResemble, []string{"c", "b"})
+		_, ok = r.remove(1)
+		So(ok, ShouldBeFalse)
+		So(r.len(), ShouldEqual, 1)
+	})
+}
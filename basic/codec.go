@@ -0,0 +1,96 @@
+package basic
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// Entry is one cache entry as it is written to a snapshot
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+	// Expiration is the absolute expiry time in unix nanoseconds,
+	// 0 never expires
+	Expiration int64
+	// Duration is the ttl the entry was set with, it drives refresh
+	Duration int64
+	// Tags are the tags of InvalidateTag the entry was set with
+	Tags []string `json:",omitempty"`
+}
+
+// Expired report whether the entry is expired at now, in unix nanoseconds
+func (e *Entry[K, V]) Expired(now int64) bool {
+	return e.Expiration != 0 && e.Expiration <= now
+}
+
+// Encoder write entries to a stream
+type Encoder[K comparable, V any] interface {
+	Encode(*Entry[K, V]) error
+}
+
+// Decoder read entries from a stream, it returns io.EOF at the end
+type Decoder[K comparable, V any] interface {
+	Decode(*Entry[K, V]) error
+}
+
+// Codec turn entries into bytes and back for Snapshot and Restore
+type Codec[K comparable, V any] interface {
+	NewEncoder(io.Writer) Encoder[K, V]
+	NewDecoder(io.Reader) Decoder[K, V]
+}
+
+// GobCodec encode entries with encoding/gob, the default codec.
+// Interface values have to be registered with gob.Register.
+type GobCodec[K comparable, V any] struct{}
+
+type gobEncoder[K comparable, V any] struct {
+	enc *gob.Encoder
+}
+
+func (e gobEncoder[K, V]) Encode(entry *Entry[K, V]) error {
+	return e.enc.Encode(entry)
+}
+
+type gobDecoder[K comparable, V any] struct {
+	dec *gob.Decoder
+}
+
+func (d gobDecoder[K, V]) Decode(entry *Entry[K, V]) error {
+	return d.dec.Decode(entry)
+}
+
+func (GobCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return gobEncoder[K, V]{gob.NewEncoder(w)}
+}
+
+func (GobCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return gobDecoder[K, V]{gob.NewDecoder(r)}
+}
+
+// JSONCodec encode entries as a stream of JSON objects, one per line
+type JSONCodec[K comparable, V any] struct{}
+
+type jsonEncoder[K comparable, V any] struct {
+	enc *json.Encoder
+}
+
+func (e jsonEncoder[K, V]) Encode(entry *Entry[K, V]) error {
+	return e.enc.Encode(entry)
+}
+
+type jsonDecoder[K comparable, V any] struct {
+	dec *json.Decoder
+}
+
+func (d jsonDecoder[K, V]) Decode(entry *Entry[K, V]) error {
+	return d.dec.Decode(entry)
+}
+
+func (JSONCodec[K, V]) NewEncoder(w io.Writer) Encoder[K, V] {
+	return jsonEncoder[K, V]{json.NewEncoder(w)}
+}
+
+func (JSONCodec[K, V]) NewDecoder(r io.Reader) Decoder[K, V] {
+	return jsonDecoder[K, V]{json.NewDecoder(r)}
+}
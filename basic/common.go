@@ -23,15 +23,21 @@ func (*noCopy) Lock() {}
 type Type int8
 
 const (
-	LRU Type = iota
-	LFU      = iota
-	ARC      = iota
+	LRU      Type = iota
+	LFU           = iota
+	ARC           = iota
+	WTinyLFU Type = iota
+	S3FIFO   Type = iota
+	TwoQ     Type = iota
+	SLRU     Type = iota
 )
 
 var (
 	NotFound = errors.New("not found")
 	Timeout  = errors.New("timeout")
 	Disuse   = errors.New("disuse")
+	// NoCallback is returned by Warm on a cache without a callback
+	NoCallback = errors.New("no callback")
 )
 
 func randfunc(t, d int64) bool {
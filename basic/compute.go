@@ -0,0 +1,145 @@
+package basic
+
+// Action is what Compute does with the value returned by its function
+type Action int8
+
+const (
+	// Keep leave the entry as it is, or absent
+	Keep Action = iota
+	// Update store the returned value with the default ttl
+	Update
+	// Remove delete the entry
+	Remove
+)
+
+// Compute call fn with the value of k, exists is false when k is missing
+// or expired, and apply the Action it returns, all under the shard lock:
+// concurrent read-modify-writes of k never lose an update. fn must not
+// use the cache. It returns the value of k afterwards and whether it is
+// present; tags of an updated entry are kept.
+func (c *PartitionCache[K, V]) Compute(k K, fn func(old V, exists bool) (V, Action)) (V, bool) {
+	h := ehash(k)
+	return c.table.Load().bucket(h).compute(c, nil, k, h, func(old V, _ uint64, exists bool) (V, Action) {
+		return fn(old, exists)
+	})
+}
+
+// SetIfAbsent store v unless k is present, it reports whether v was stored
+func (c *PartitionCache[K, V]) SetIfAbsent(k K, v V) bool {
+	stored := false
+	c.Compute(k, func(old V, exists bool) (V, Action) {
+		if exists {
+			return old, Keep
+		}
+		stored = true
+		return v, Update
+	})
+	return stored
+}
+
+// GetOrSet return the value of k, storing v first when k is absent.
+// loaded reports whether the value was already there.
+func (c *PartitionCache[K, V]) GetOrSet(k K, v V) (r V, loaded bool) {
+	r, _ = c.Compute(k, func(old V, exists bool) (V, Action) {
+		if exists {
+			loaded = true
+			return old, Keep
+		}
+		return v, Update
+	})
+	return r, loaded
+}
+
+// GetVersion return the value of k with its version, which changes on
+// every write of k, for CompareAndSwapVersion. The callback is not used.
+// error maybe not found, timeout
+func (c *PartitionCache[K, V]) GetVersion(k K) (r V, version uint64, err error) {
+	h := ehash(k)
+	b := c.table.Load().bucket(h).rlock(h)
+	item, ok := b.items[h]
+	epoch := b.stamps.epoch.Load()
+	b.mu.RUnlock()
+	if !ok || !item.owned(k, nil, epoch) {
+		return r, 0, NotFound
+	}
+	if item.expiredAt(b.now()) {
+		return item.obj, item.version, Timeout
+	}
+	return item.obj, item.version, nil
+}
+
+// CompareAndSwapVersion store v if k is present with version, it works
+// for any V, see CompareAndSwap
+func (c *PartitionCache[K, V]) CompareAndSwapVersion(k K, version uint64, v V) bool {
+	swapped := false
+	h := ehash(k)
+	c.table.Load().bucket(h).compute(c, nil, k, h, func(old V, current uint64, exists bool) (V, Action) {
+		if !exists || current != version {
+			return old, Keep
+		}
+		swapped = true
+		return v, Update
+	})
+	return swapped
+}
+
+// CompareAndSwap store new if the value of k is old, it reports whether
+// it did. V must be comparable, CompareAndSwapVersion works for any V.
+func CompareAndSwap[K comparable, V comparable](c *PartitionCache[K, V], k K, old, new V) bool {
+	swapped := false
+	c.Compute(k, func(v V, exists bool) (V, Action) {
+		if !exists || v != old {
+			return v, Keep
+		}
+		swapped = true
+		return new, Update
+	})
+	return swapped
+}
+
+// Compute is PartitionCache.Compute within the namespace
+func (ns *Namespace[K, V]) Compute(k K, fn func(old V, exists bool) (V, Action)) (V, bool) {
+	h := ns.hash(k)
+	return ns.c.table.Load().bucket(h).compute(ns.c, ns, k, h, func(old V, _ uint64, exists bool) (V, Action) {
+		return fn(old, exists)
+	})
+}
+
+// compute run fn on k of namespace ns under the write lock, fn also gets
+// the version of the entry
+func (b *bucket[K, V]) compute(p *PartitionCache[K, V], ns *Namespace[K, V], k K, h uintptr, fn func(V, uint64, bool) (V, Action)) (r V, ok bool) {
+	w := p.wal.Load()
+	b = b.lock(h)
+	i, found := b.items[h]
+	owned := found && i.owned(k, ns, b.stamps.epoch.Load())
+	exists := owned && !i.expiredAt(b.now())
+	var old V
+	var version uint64
+	if exists {
+		old, version = i.obj, i.version
+	}
+	v, act := fn(old, version, exists)
+	var seq uint64
+	switch act {
+	case Update:
+		item := p.item(ns, k, v, p.durationOf(ns), nil)
+		if exists {
+			item.tags = i.tags
+		}
+		if ok, seq = b.store(h, item, w); ok {
+			r = v
+		}
+	case Remove:
+		if owned {
+			b.remove(h, i)
+			if ns == nil {
+				seq = w.Append(OpDelete, &Entry[K, V]{Key: k})
+			}
+		}
+	default:
+		r, ok = old, exists
+	}
+	b.mu.Unlock()
+	w.Wait(seq)
+	return r, ok
+}
@@ -0,0 +1,130 @@
+package basic
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCompute(t *testing.T) {
+	Convey("concurrent computes never lose an update", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4))
+		defer cache.Close()
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 500; i++ {
+					cache.Compute("n", func(old int, exists bool) (int, Action) {
+						return old + 1, Update
+					})
+				}
+			}()
+		}
+		// a resize moves the shard under the computes
+		cache.Resize(16)
+		wg.Wait()
+		v, err := cache.Get("n")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 4000)
+	})
+
+	Convey("compute keeps, removes and sees expired entries as absent", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		v, ok := cache.Compute("a", func(old int, exists bool) (int, Action) {
+			So(exists, ShouldBeFalse)
+			return 1, Keep
+		})
+		So(ok, ShouldBeFalse)
+		So(v, ShouldEqual, 0)
+		cache.SetWithTags("a", 1, time.Minute, "t")
+		v, ok = cache.Compute("a", func(old int, exists bool) (int, Action) {
+			return old + 1, Update
+		})
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 2)
+		So(cache.idx.Tag("t"), ShouldResemble, []string{"a"})
+		_, ok = cache.Compute("a", func(old int, exists bool) (int, Action) {
+			return old, Remove
+		})
+		So(ok, ShouldBeFalse)
+		_, err := cache.Get("a")
+		So(err, ShouldEqual, NotFound)
+		So(cache.idx.Tag("t"), ShouldBeEmpty)
+		cache.SetWithExp("b", 5, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		cache.Compute("b", func(old int, exists bool) (int, Action) {
+			So(exists, ShouldBeFalse)
+			So(old, ShouldEqual, 0)
+			return 1, Update
+		})
+		v, err = cache.Get("b")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+	})
+
+	Convey("set if absent and get or set", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.SetIfAbsent("a", 1), ShouldBeTrue)
+		So(cache.SetIfAbsent("a", 2), ShouldBeFalse)
+		v, loaded := cache.GetOrSet("a", 3)
+		So(loaded, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+		v, loaded = cache.GetOrSet("b", 3)
+		So(loaded, ShouldBeFalse)
+		So(v, ShouldEqual, 3)
+	})
+
+	Convey("compare and swap by value and by version", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(CompareAndSwap(cache, "a", 0, 1), ShouldBeFalse)
+		cache.Set("a", 1)
+		So(CompareAndSwap(cache, "a", 2, 3), ShouldBeFalse)
+		So(CompareAndSwap(cache, "a", 1, 3), ShouldBeTrue)
+		v, _ := cache.Get("a")
+		So(v, ShouldEqual, 3)
+
+		slices := NewPartitionCache[string, []int]()
+		defer slices.Close()
+		slices.Set("s", []int{1})
+		old, version, err := slices.GetVersion("s")
+		So(err, ShouldBeNil)
+		So(slices.CompareAndSwapVersion("s", version, append(old, 2)), ShouldBeTrue)
+		So(slices.CompareAndSwapVersion("s", version, []int{0}), ShouldBeFalse)
+		s, next, _ := slices.GetVersion("s")
+		So(s, ShouldResemble, []int{1, 2})
+		So(next, ShouldBeGreaterThan, version)
+		// a deleted and recreated key gets a new version
+		slices.Delete("s")
+		slices.Set("s", []int{1, 2})
+		So(slices.CompareAndSwapVersion("s", next, nil), ShouldBeFalse)
+		_, _, err = slices.GetVersion("missing")
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("namespaces compute on their own entries", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		ns := cache.Namespace("n")
+		ns.WithQuota(1)
+		cache.Set("a", 10)
+		v, ok := ns.Compute("a", func(old int, exists bool) (int, Action) {
+			return old + 1, Update
+		})
+		So(ok, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+		_, ok = ns.Compute("b", func(old int, exists bool) (int, Action) {
+			return 1, Update
+		})
+		So(ok, ShouldBeFalse)
+		So(ns.Stats().Rejected, ShouldEqual, 1)
+		v, _ = cache.Get("a")
+		So(v, ShouldEqual, 10)
+	})
+}
@@ -0,0 +1,270 @@
+package basic
+
+import (
+	"container/heap"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// HotKey is a tracked key with its estimated number of sampled Gets.
+// The true count is between Count-Error and Count.
+type HotKey[K comparable] struct {
+	Key   K
+	Count uint64
+	Error uint64
+	// Shard is the shard the key maps to
+	Shard int
+}
+
+// HotKeyStats is the view of the tracker on the traffic of a cache
+type HotKeyStats struct {
+	// Sample is the rate of recorded Gets, one in Sample
+	Sample int
+	// Sampled counts the recorded Gets since the last aging
+	Sampled uint64
+	// Shards counts the recorded Gets of every shard
+	Shards []uint64
+	// Imbalance is the load of the busiest shard over the mean, 1 is even
+	Imbalance float64
+	// Hottest is the busiest shard
+	Hottest int
+}
+
+// HotKeyTracker find the heaviest keys of the Get traffic with the
+// Space-Saving algorithm: it keeps k counters, a key without one takes
+// over the smallest and inherits its count as error. Only one Get in
+// sample is recorded, so the read path pays for a random number and
+// rarely for a lock. Every shard of the cache has its own table of k
+// counters and its own lock, a key only ever lands in the table of its
+// shard, so the tables are merged for Top without losing a key. Every
+// 1000*k samples all counts are halved, so keys that cooled down leave
+// the top.
+type HotKeyTracker[K comparable] struct {
+	mu      sync.Mutex
+	k       int
+	sample  int
+	window  uint64
+	pending atomic.Uint64
+	tables  atomic.Pointer[[]hotTable[K]]
+}
+
+// hotTable is the Space-Saving table of one shard
+type hotTable[K comparable] struct {
+	mu       sync.Mutex
+	sampled  uint64
+	counters map[K]*hotCounter[K]
+	heap     hotHeap[K]
+}
+
+type hotCounter[K comparable] struct {
+	key   K
+	count uint64
+	err   uint64
+	index int
+}
+
+// NewHotKeyTracker new tracker of k keys recording one Get in sample,
+// nil when k is 0
+func NewHotKeyTracker[K comparable](k, sample int) *HotKeyTracker[K] {
+	if k <= 0 {
+		return nil
+	}
+	if sample <= 0 {
+		sample = 16
+	}
+	t := &HotKeyTracker[K]{
+		k:      k,
+		sample: sample,
+		window: 1000 * uint64(k),
+	}
+	t.tables.Store(&[]hotTable[K]{})
+	return t
+}
+
+// tablesOf return the tables of shards shards, starting over when the
+// cache was resized: the old mapping says nothing anymore
+func (t *HotKeyTracker[K]) tablesOf(shards int) []hotTable[K] {
+	if tables := *t.tables.Load(); len(tables) == shards {
+		return tables
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tables := *t.tables.Load()
+	if len(tables) != shards {
+		tables = make([]hotTable[K], shards)
+		t.tables.Store(&tables)
+		t.pending.Store(0)
+	}
+	return tables
+}
+
+// Observe record a Get of k on shard out of shards, once in sample, and
+// report whether it did. Only the table of shard is locked. A nil
+// tracker does nothing.
+func (t *HotKeyTracker[K]) Observe(k K, shard, shards int) bool {
+	if t == nil || (t.sample > 1 && rand.IntN(t.sample) != 0) {
+		return false
+	}
+	tb := &t.tablesOf(shards)[shard]
+	tb.mu.Lock()
+	tb.observe(k, t.k)
+	tb.mu.Unlock()
+	if t.pending.Add(1) == t.window {
+		t.age()
+	}
+	return true
+}
+
+// observe count k in tb, which must be locked
+func (tb *hotTable[K]) observe(k K, n int) {
+	tb.sampled++
+	if c, ok := tb.counters[k]; ok {
+		c.count++
+		heap.Fix(&tb.heap, c.index)
+	} else if len(tb.heap) < n {
+		if tb.counters == nil {
+			tb.counters = make(map[K]*hotCounter[K], n)
+		}
+		c := &hotCounter[K]{key: k, count: 1}
+		tb.counters[k] = c
+		heap.Push(&tb.heap, c)
+	} else {
+		c := tb.heap[0]
+		delete(tb.counters, c.key)
+		c.key, c.err = k, c.count
+		c.count++
+		tb.counters[k] = c
+		heap.Fix(&tb.heap, 0)
+	}
+}
+
+// age halve every count of every table, the heap order is kept
+func (t *HotKeyTracker[K]) age() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tables := *t.tables.Load()
+	var sampled uint64
+	for i := range tables {
+		tb := &tables[i]
+		tb.mu.Lock()
+		for _, c := range tb.heap {
+			c.count /= 2
+			c.err /= 2
+		}
+		tb.sampled /= 2
+		sampled += tb.sampled
+		tb.mu.Unlock()
+	}
+	t.pending.Store(sampled)
+}
+
+// Top return the n hottest keys, at most k, hottest first. A nil tracker
+// has none.
+func (t *HotKeyTracker[K]) Top(n int) []HotKey[K] {
+	if t == nil {
+		return nil
+	}
+	tables := *t.tables.Load()
+	var top []HotKey[K]
+	for i := range tables {
+		tb := &tables[i]
+		tb.mu.Lock()
+		for _, c := range tb.heap {
+			top = append(top, HotKey[K]{Key: c.key, Count: c.count, Error: c.err, Shard: i})
+		}
+		tb.mu.Unlock()
+	}
+	sort.Slice(top, func(i, j int) bool { return top[i].Count > top[j].Count })
+	if n < 0 || n > t.k {
+		n = t.k
+	}
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// Stats return the per shard load, the zero value for a nil tracker
+func (t *HotKeyTracker[K]) Stats() HotKeyStats {
+	if t == nil {
+		return HotKeyStats{}
+	}
+	tables := *t.tables.Load()
+	s := HotKeyStats{Sample: t.sample}
+	if len(tables) > 0 {
+		s.Shards = make([]uint64, len(tables))
+	}
+	for i := range tables {
+		tb := &tables[i]
+		tb.mu.Lock()
+		s.Shards[i] = tb.sampled
+		tb.mu.Unlock()
+	}
+	var max uint64
+	for i, n := range s.Shards {
+		s.Sampled += n
+		if n > max {
+			max, s.Hottest = n, i
+		}
+	}
+	if s.Sampled > 0 {
+		s.Imbalance = float64(max) * float64(len(s.Shards)) / float64(s.Sampled)
+	}
+	return s
+}
+
+// Reset forget every key and count
+func (t *HotKeyTracker[K]) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.tables.Store(&[]hotTable[K]{})
+	t.pending.Store(0)
+	t.mu.Unlock()
+}
+
+// hotHeap is a min-heap of counters, the smallest is replaced first
+type hotHeap[K comparable] []*hotCounter[K]
+
+func (h hotHeap[K]) Len() int           { return len(h) }
+func (h hotHeap[K]) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotHeap[K]) Push(x any) {
+	c := x.(*hotCounter[K])
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *hotHeap[K]) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// HotKeys return the n hottest keys of the Gets of the cache itself,
+// namespaces not included. It is nil unless WithHotKeys is given.
+func (c *PartitionCache[K, V]) HotKeys(n int) []HotKey[K] {
+	return c.hot.Top(n)
+}
+
+// HotKeyStats return the load of every shard as seen by the hot key
+// tracker, against the current ehash to shard mapping
+func (c *PartitionCache[K, V]) HotKeyStats() HotKeyStats {
+	return c.hot.Stats()
+}
+
+// Replicated return the keys served from a lock-free copy, see
+// WithHotKeyReplicas
+func (c *PartitionCache[K, V]) Replicated() []K {
+	return c.replicas.Keys()
+}
@@ -0,0 +1,118 @@
+package basic
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHotKeyTracker(t *testing.T) {
+	Convey("space saving finds the heavy keys of a skewed workload", t, func() {
+		tr := NewHotKeyTracker[string](8, 1)
+		for i := 0; i < 1000; i++ {
+			tr.Observe("a", 0, 2)
+			if i%2 == 0 {
+				tr.Observe("b", 1, 2)
+			}
+			tr.Observe(fmt.Sprint("cold", i), i%2, 2)
+		}
+		top := tr.Top(2)
+		So(len(top), ShouldEqual, 2)
+		So(top[0].Key, ShouldEqual, "a")
+		So(top[1].Key, ShouldEqual, "b")
+		for _, k := range top {
+			So(k.Count-k.Error, ShouldBeLessThanOrEqualTo, 1000)
+		}
+		So(top[0].Count, ShouldBeGreaterThanOrEqualTo, 1000)
+		So(len(tr.Top(-1)), ShouldEqual, 8)
+	})
+
+	Convey("aging halves the counts", t, func() {
+		tr := NewHotKeyTracker[int](1, 1)
+		for i := 0; i < 999; i++ {
+			tr.Observe(1, 0, 1)
+		}
+		So(tr.Top(1)[0].Count, ShouldEqual, 999)
+		tr.Observe(1, 0, 1)
+		So(tr.Top(1)[0].Count, ShouldEqual, 500)
+		So(tr.Stats().Sampled, ShouldEqual, 500)
+	})
+
+	Convey("one hot key loads one shard", t, func() {
+		tr := NewHotKeyTracker[int](8, 1)
+		for i := 0; i < 100; i++ {
+			tr.Observe(7, 3, 4)
+		}
+		s := tr.Stats()
+		So(s.Shards, ShouldResemble, []uint64{0, 0, 0, 100})
+		So(s.Hottest, ShouldEqual, 3)
+		So(s.Imbalance, ShouldEqual, 4)
+		// another shard count resets the load
+		tr.Observe(7, 1, 8)
+		So(tr.Stats().Shards, ShouldResemble, []uint64{0, 1, 0, 0, 0, 0, 0, 0})
+		tr.Reset()
+		So(tr.Top(10), ShouldBeEmpty)
+	})
+
+	Convey("every shard counts in its own table, merged by Top", t, func() {
+		tr := NewHotKeyTracker[int](2, 1)
+		var wg sync.WaitGroup
+		for shard := 0; shard < 4; shard++ {
+			wg.Add(1)
+			go func(shard int) {
+				defer wg.Done()
+				for i := 0; i < 100*(shard+1); i++ {
+					tr.Observe(shard, shard, 4)
+				}
+			}(shard)
+		}
+		wg.Wait()
+		tables := *tr.tables.Load()
+		So(tables, ShouldHaveLength, 4)
+		for i := range tables {
+			So(tables[i].heap, ShouldHaveLength, 1)
+			So(tables[i].heap[0].key, ShouldEqual, i)
+		}
+		top := tr.Top(-1)
+		So(top, ShouldHaveLength, 2)
+		So(top[0], ShouldResemble, HotKey[int]{Key: 3, Count: 400, Shard: 3})
+		So(top[1].Key, ShouldEqual, 2)
+		So(tr.Stats().Sampled, ShouldEqual, 1000)
+	})
+
+	Convey("tracking is off without the option", t, func() {
+		var tr *HotKeyTracker[int]
+		So(NewHotKeyTracker[int](0, 1), ShouldBeNil)
+		tr.Observe(1, 0, 1)
+		So(tr.Top(1), ShouldBeNil)
+		So(tr.Stats().Sample, ShouldEqual, 0)
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		cache.Set("a", 1)
+		cache.Get("a")
+		So(cache.HotKeys(10), ShouldBeNil)
+	})
+
+	Convey("the cache records its Gets", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4), WithHotKeys(4, 1))
+		defer cache.Close()
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		for i := 0; i < 10; i++ {
+			cache.Get("a")
+		}
+		cache.Get("b")
+		top := cache.HotKeys(1)
+		So(top[0].Key, ShouldEqual, "a")
+		So(top[0].Count, ShouldEqual, 10)
+		s := cache.HotKeyStats()
+		So(len(s.Shards), ShouldEqual, 4)
+		So(s.Sampled, ShouldEqual, 11)
+		So(s.Shards[top[0].Shard], ShouldBeGreaterThanOrEqualTo, 10)
+		cache.Resize(8)
+		cache.Get("a")
+		So(len(cache.HotKeyStats().Shards), ShouldEqual, 8)
+	})
+}
@@ -0,0 +1,265 @@
+package basic
+
+import "iter"
+
+// ShardSeq yield the entries collect gathers from each of n shards. A
+// shard is collected under its lock and yielded once the lock is released,
+// so the caller of the iterator may use the cache.
+func ShardSeq[K comparable, V any](n int, collect func(i int, dst []Entry[K, V]) []Entry[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var batch []Entry[K, V]
+		for i := 0; i < n; i++ {
+			batch = collect(i, batch[:0])
+			for _, e := range batch {
+				if !yield(e.Key, e.Value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys collect the keys of seq
+func Keys[K comparable, V any](seq iter.Seq2[K, V]) []K {
+	var ks []K
+	for k := range seq {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+// All iterate over the live entries, expired ones are skipped. Shards are
+// read-locked one at a time, entries set meanwhile may or may not show up.
+func (c *PartitionCache[K, V]) All() iter.Seq2[K, V] {
+	t := c.table.Load()
+	return ShardSeq(len(t.buckets), func(i int, dst []Entry[K, V]) []Entry[K, V] {
+		now := c.stamps.clock.Now()
+		t.walk(i, nil, func(_ uintptr, item TemplateItem[K, V], epoch uint64) {
+			if item.ns == nil && !item.stale(epoch) && !item.expiredAt(now) {
+				dst = append(dst, Entry[K, V]{Key: item.k, Value: item.obj})
+			}
+		})
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *PartitionCache[K, V]) Range(fn func(K, V) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries, see All
+func (c *PartitionCache[K, V]) Keys() []K {
+	return Keys(c.All())
+}
+
+// Len return the number of live entries, namespaces not included. It
+// walks the shards like All.
+func (c *PartitionCache[K, V]) Len() int {
+	t := c.table.Load()
+	now := c.stamps.clock.Now()
+	n := 0
+	for i := range t.buckets {
+		t.walk(i, nil, func(_ uintptr, item TemplateItem[K, V], epoch uint64) {
+			if item.ns == nil && !item.stale(epoch) && !item.expiredAt(now) {
+				n++
+			}
+		})
+	}
+	return n
+}
+
+// walk call fn for the entries of shard i that keep accepts, under its
+// read lock. When Resize already moved the shard, its entries are found
+// in the shards of the next table they were spread over.
+func (t *partitionTable[K, V]) walk(i int, keep func(uintptr) bool, fn func(uintptr, TemplateItem[K, V], uint64)) {
+	b := &t.buckets[i]
+	b.mu.RLock()
+	next := b.moved
+	if next == nil {
+		epoch := b.stamps.epoch.Load()
+		for h, item := range b.items {
+			if keep == nil || keep(h) {
+				fn(h, item, epoch)
+			}
+		}
+		b.mu.RUnlock()
+		return
+	}
+	b.mu.RUnlock()
+	from := func(h uintptr) bool {
+		return h&t.mask == uintptr(i) && (keep == nil || keep(h))
+	}
+	m := t.mask & next.mask
+	for j := range next.buckets {
+		if uintptr(j)&m == uintptr(i)&m {
+			next.walk(j, from, fn)
+		}
+	}
+}
+
+// expiredAt is Expired at now
+func (i TemplateItem[K, V]) expiredAt(now int64) bool {
+	return i.expiration != 0 && now > i.expiration
+}
+
+// All iterate over the live entries, expired ones are skipped. They are
+// collected under the read lock, then yielded without it.
+func (c *TemplateCache[K, V]) All() iter.Seq2[K, V] {
+	return ShardSeq(1, func(_ int, dst []Entry[K, V]) []Entry[K, V] {
+		now := c.clock.Now()
+		c.mu.RLock()
+		for k, item := range c.items {
+			if !item.expiredAt(now) {
+				dst = append(dst, Entry[K, V]{Key: k, Value: item.obj})
+			}
+		}
+		c.mu.RUnlock()
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *TemplateCache[K, V]) Range(fn func(K, V) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries
+func (c *TemplateCache[K, V]) Keys() []K {
+	return Keys(c.All())
+}
+
+// Len return the number of live entries
+func (c *TemplateCache[K, V]) Len() int {
+	now := c.clock.Now()
+	n := 0
+	c.mu.RLock()
+	for _, item := range c.items {
+		if !item.expiredAt(now) {
+			n++
+		}
+	}
+	c.mu.RUnlock()
+	return n
+}
+
+// All iterate over the live entries from the oldest to the newest,
+// expired ones are skipped. They are collected under the read lock, then
+// yielded without it.
+func (c *SieveCache[K, V]) All() iter.Seq2[K, V] {
+	return ShardSeq(1, func(_ int, dst []Entry[K, V]) []Entry[K, V] {
+		now := c.clock.Now()
+		c.mu.RLock()
+		for n := c.tail; n != nil; n = n.prev {
+			if !n.expiredAt(now) {
+				dst = append(dst, Entry[K, V]{Key: n.k, Value: n.obj})
+			}
+		}
+		c.mu.RUnlock()
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *SieveCache[K, V]) Range(fn func(K, V) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries
+func (c *SieveCache[K, V]) Keys() []K {
+	return Keys(c.All())
+}
+
+// Len return the number of live entries
+func (c *SieveCache[K, V]) Len() int {
+	now := c.clock.Now()
+	n := 0
+	c.mu.RLock()
+	for _, node := range c.items {
+		if !node.expiredAt(now) {
+			n++
+		}
+	}
+	c.mu.RUnlock()
+	return n
+}
+
+// All iterate over the live entries, expired ones are skipped. They are
+// collected under the read lock, then yielded without it.
+func (c *SimpleCache) All() iter.Seq2[string, any] {
+	return ShardSeq(1, func(_ int, dst []Entry[string, any]) []Entry[string, any] {
+		now := c.clock.Now()
+		c.mu.RLock()
+		for k, item := range c.items {
+			if !item.expiredAt(now) {
+				dst = append(dst, Entry[string, any]{Key: k, Value: item.obj})
+			}
+		}
+		c.mu.RUnlock()
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *SimpleCache) Range(fn func(string, any) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries
+func (c *SimpleCache) Keys() []string {
+	return Keys(c.All())
+}
+
+// Len return the number of live entries
+func (c *SimpleCache) Len() int {
+	now := c.clock.Now()
+	n := 0
+	c.mu.RLock()
+	for _, item := range c.items {
+		if !item.expiredAt(now) {
+			n++
+		}
+	}
+	c.mu.RUnlock()
+	return n
+}
+
+// All iterate over the live entries, expired ones are skipped. They are
+// collected under the read lock, then yielded without it.
+func (c *LRUCache) All() iter.Seq2[string, any] {
+	return ShardSeq(1, func(_ int, dst []Entry[string, any]) []Entry[string, any] {
+		now := c.clock.Now()
+		c.mu.RLock()
+		for k, item := range c.items {
+			if !item.expiredAt(now) {
+				dst = append(dst, Entry[string, any]{Key: k, Value: item.obj})
+			}
+		}
+		c.mu.RUnlock()
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *LRUCache) Range(fn func(string, any) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries
+func (c *LRUCache) Keys() []string {
+	return Keys(c.All())
+}
+
+// Len return the number of live entries
+func (c *LRUCache) Len() int {
+	now := c.clock.Now()
+	n := 0
+	c.mu.RLock()
+	for _, item := range c.items {
+		if !item.expiredAt(now) {
+			n++
+		}
+	}
+	c.mu.RUnlock()
+	return n
+}
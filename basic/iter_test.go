@@ -0,0 +1,107 @@
+package basic
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIter(t *testing.T) {
+	Convey("partition cache walks live entries of every shard", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(8))
+		defer cache.Close()
+		for i := 0; i < 100; i++ {
+			cache.Set(i, i*2)
+		}
+		cache.SetWithExp(1000, 1, time.Nanosecond)
+		cache.Namespace("n").Set(2000, 1)
+		time.Sleep(time.Millisecond)
+		So(cache.Len(), ShouldEqual, 100)
+		sum := 0
+		for k, v := range cache.All() {
+			So(v, ShouldEqual, k*2)
+			sum += k
+		}
+		So(sum, ShouldEqual, 4950)
+		ks := cache.Keys()
+		sort.Ints(ks)
+		So(len(ks), ShouldEqual, 100)
+		So(ks[99], ShouldEqual, 99)
+		n := 0
+		cache.Range(func(k, v int) bool {
+			n++
+			return n < 10
+		})
+		So(n, ShouldEqual, 10)
+		cache.InvalidateAll()
+		So(cache.Len(), ShouldEqual, 0)
+	})
+
+	Convey("a walk during resize sees every entry once", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(4))
+		defer cache.Close()
+		for i := 0; i < 1000; i++ {
+			cache.Set(i, i)
+		}
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, n := range []int{64, 2, 32, 8} {
+				cache.Resize(n)
+			}
+		}()
+		for r := 0; r < 20; r++ {
+			seen := make(map[int]int)
+			for k := range cache.All() {
+				seen[k]++
+			}
+			So(len(seen), ShouldEqual, 1000)
+			for _, c := range seen {
+				if c != 1 {
+					So(c, ShouldEqual, 1)
+				}
+			}
+		}
+		wg.Wait()
+	})
+
+	Convey("the single lock caches walk their live entries", t, func() {
+		tc := NewTemplateCache[string, int]()
+		sc := NewSieveCache[string, int](10)
+		simple := NewSimpleCache()
+		lru := NewLRUCache(10)
+		for i := 0; i < 5; i++ {
+			k := strconv.Itoa(i)
+			tc.Set(k, i)
+			sc.Set(k, i)
+			simple.Set(k, i)
+			lru.Set(k, i)
+		}
+		tc.SetWithExp("x", 0, time.Nanosecond)
+		sc.SetWithExp("x", 0, time.Nanosecond)
+		simple.SetWithExp("x", 0, time.Nanosecond)
+		lru.SetWithExp("x", 0, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		So(tc.Len(), ShouldEqual, 5)
+		So(sc.Len(), ShouldEqual, 5)
+		So(simple.Len(), ShouldEqual, 5)
+		So(lru.Len(), ShouldEqual, 5)
+		So(sc.Keys(), ShouldResemble, []string{"0", "1", "2", "3", "4"})
+		for _, ks := range [][]string{tc.Keys(), simple.Keys(), lru.Keys()} {
+			sort.Strings(ks)
+			So(ks, ShouldResemble, []string{"0", "1", "2", "3", "4"})
+		}
+		for k, v := range simple.All() {
+			So(fmt.Sprint(v), ShouldEqual, k)
+		}
+		n := 0
+		tc.Range(func(string, int) bool { n++; return false })
+		So(n, ShouldEqual, 1)
+	})
+}
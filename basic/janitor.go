@@ -2,37 +2,66 @@ package basic
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
 type Janitor struct {
 	Interval time.Duration
-	stop     chan bool
+	clock    Clock
+	stop     func()
+	mu       sync.Mutex
+	done     chan struct{}
 }
 
+// Run call del every Interval of the clock of j, the system clock for a
+// Janitor built by hand, until Stop
 func (j *Janitor) Run(del func()) {
-	ticker := time.NewTicker(j.Interval)
-	for {
-		select {
-		case <-ticker.C:
-			del()
-		case <-j.stop:
-			ticker.Stop()
-			return
-		}
+	clock := j.clock
+	if clock == nil {
+		clock = SystemClock
 	}
+	stop := clock.Every(j.Interval, del)
+	<-j.stopped()
+	stop()
+}
+
+func (j *Janitor) stopped() chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done == nil {
+		j.done = make(chan struct{})
+	}
+	return j.done
 }
 
 func (j *Janitor) Stop() {
 	fmt.Println("janitor stop")
-	j.stop <- true
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done == nil {
+		j.done = make(chan struct{})
+	}
+	select {
+	case <-j.done:
+	default:
+		close(j.done)
+		if j.stop != nil {
+			j.stop()
+		}
+	}
 }
 
+// NewJanitor call del every ci of the system clock
 func NewJanitor(ci time.Duration, del func()) *Janitor {
-	j := &Janitor{
+	return NewClockJanitor(SystemClock, ci, del)
+}
+
+// NewClockJanitor call del every ci of clock
+func NewClockJanitor(clock Clock, ci time.Duration, del func()) *Janitor {
+	return &Janitor{
 		Interval: ci,
-		stop:     make(chan bool),
+		clock:    clock,
+		stop:     clock.Every(ci, del),
 	}
-	go j.Run(del)
-	return j
 }
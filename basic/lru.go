@@ -2,6 +2,7 @@ package basic
 
 import (
 	"container/list"
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
@@ -10,6 +11,13 @@ import (
 
 const (
 	DeleteNums = 10
+	// StaleDeleteNums is the budget of the janitor for the entries
+	// invalidated by InvalidateAll or Bump, per shard and per pass
+	StaleDeleteNums = 1024
+	// ScanNums is the most entries the janitor looks at, per shard and per
+	// pass, so it holds the write lock for a bounded time. Maps are ranged
+	// from a random place, every pass looks at another part of the shard.
+	ScanNums = 2 * StaleDeleteNums
 )
 
 type LRUList struct {
@@ -26,7 +34,8 @@ type LRUItem struct {
 	p          *list.Element
 }
 
-// Expired is expired data
+// Expired is expired data by the system clock, the cache checks its
+// entries against the clock of its options
 func (i *LRUItem) Expired() bool {
 	if i.expiration == 0 {
 		return false
@@ -34,6 +43,11 @@ func (i *LRUItem) Expired() bool {
 	return time.Now().UnixNano() > i.expiration
 }
 
+// expiredAt is Expired at now
+func (i *LRUItem) expiredAt(now int64) bool {
+	return i.expiration != 0 && now > i.expiration
+}
+
 // Disuse is disuse data
 func (i *LRUItem) Disuse() bool {
 	if i.color != black {
@@ -53,18 +67,23 @@ type LRUCache struct {
 	caller          func(string) (interface{}, error)
 	size            uint32
 	order           *list.List
+	clock           Clock
+	codec           Codec[string, any]
 }
 
-// NewLRUCache new cache
-func NewLRUCache(size uint32) *LRUCache {
+// NewLRUCache new cache. Of opts only WithClock applies, it drives the
+// expirations and the janitor.
+func NewLRUCache(size uint32, opts ...Option) *LRUCache {
+	o := NewOptions(opts...)
 	c := &LRUCache{
 		items:           make(map[string]LRUItem),
 		defaultDuration: 10 * time.Second,
 		randfunc:        randfunc,
 		order:           list.New(),
 		size:            size,
+		clock:           o.Clock,
 	}
-	j := NewJanitor(1*time.Second, c.deleteExpired)
+	j := NewClockJanitor(o.Clock, 1*time.Second, c.deleteExpired)
 	runtime.SetFinalizer(c, (*LRUCache).clean)
 	c.janitor = j
 	return c
@@ -72,11 +91,15 @@ func NewLRUCache(size uint32) *LRUCache {
 
 func (c *LRUCache) clean() {
 	fmt.Println("lru stop")
+	c.Close()
+}
+
+// Close stop the janitor of the cache, see the Close of PartitionCache
+func (c *LRUCache) Close() {
 	if c.janitor != nil {
 		c.janitor.Stop()
 		c.janitor = nil
 	}
-	c = nil
 }
 
 // WithCallback set callback
@@ -94,11 +117,11 @@ func (c *LRUCache) WithRandfunc(call func(int64, int64) bool) {
 func (c *LRUCache) Get(k string) (r any, err error) {
 	c.mu.RLock()
 	v, ok := c.items[k]
+	c.mu.RUnlock()
 	if !ok {
 		if c.caller == nil {
 			return nil, NotFound
 		}
-		c.mu.RUnlock()
 		v, err := c.caller(k)
 		if err != nil {
 			return nil, NotFound
@@ -106,15 +129,10 @@ func (c *LRUCache) Get(k string) (r any, err error) {
 		c.SetWithExp(k, v, c.defaultDuration)
 		return v, nil
 	}
-	c.mu.RUnlock()
-	if v.Expired() {
+	if v.expiredAt(c.clock.Now()) {
 		c.refresh(k, v)
 		return v.obj, Timeout
 	}
-	if v.Disuse() {
-		c.refresh(k, v)
-		return v.obj, Disuse
-	}
 	c.refresh(k, v)
 	return v.obj, nil
 }
@@ -126,21 +144,26 @@ func (c *LRUCache) Set(k string, v any) {
 
 // SetWithExp actively set LRUCache value
 func (c *LRUCache) SetWithExp(k string, v any, dur time.Duration) {
+	c.set(k, v, c.clock.Now()+int64(dur), int64(dur))
+}
+
+// set store v with an absolute expiration
+func (c *LRUCache) set(k string, v any, expiration, duration int64) {
 	c.mu.Lock()
 	i, ok := c.items[k]
 	if ok {
 		i.obj = v
-		i.expiration = time.Now().Add(dur).UnixNano()
-		i.duration = int64(dur)
-		// c.items[k] = i
+		i.expiration = expiration
+		i.duration = duration
+		c.items[k] = i
 		c.mu.Unlock()
 		return
 	}
 	c.items[k] = LRUItem{
 		obj:        v,
-		expiration: time.Now().Add(dur).UnixNano(),
-		duration:   int64(dur),
-		color:      black,
+		expiration: expiration,
+		duration:   duration,
+		color:      white,
 		p:          c.add(k),
 	}
 	c.mu.Unlock()
@@ -166,7 +189,7 @@ func (c *LRUCache) refresh(k string, i any) {
 	if c.caller == nil {
 		return
 	}
-	t := item.expiration - time.Now().UnixNano()
+	t := item.expiration - c.clock.Now()
 	if t > 0 && t*100/item.duration < 30 {
 		if c.randfunc != nil && !c.randfunc(t, item.duration) {
 			return
@@ -178,21 +201,14 @@ func (c *LRUCache) refresh(k string, i any) {
 	}
 }
 
-// Load load keys avoid concurrent large traffic penetration
+// Load load keys avoid concurrent large traffic penetration,
+// see Warm for the concurrency, rate limit and failures
 func (c *LRUCache) Load(ks []string) {
-	if c.caller == nil {
-		return
-	}
-	for _, k := range ks {
-		v, err := c.caller(k)
-		if err == nil {
-			c.SetWithExp(k, v, c.defaultDuration)
-		}
-	}
+	c.Warm(context.Background(), ks)
 }
 
 func (c *LRUCache) deleteExpired() {
-	now := time.Now().UnixNano()
+	now := c.clock.Now()
 	i := 0
 	c.mu.Lock()
 	for k, item := range c.items {
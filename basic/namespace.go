@@ -0,0 +1,297 @@
+package basic
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// NamespaceStats holds the counters of a namespace
+type NamespaceStats struct {
+	Hits       uint64
+	Misses     uint64
+	Loads      uint64
+	LoadErrors uint64
+	// Rejected is the number of new keys dropped by the quota
+	Rejected uint64
+	// Len is the number of live entries, approximate while a Flush runs
+	Len int64
+}
+
+// NamespaceCounters count the traffic of a namespace, every method is a
+// no-op on nil, which stands for the cache itself
+type NamespaceCounters struct {
+	hits, misses, loads, loadErrors, rejected atomic.Uint64
+}
+
+func (s *NamespaceCounters) Hit() {
+	if s != nil {
+		s.hits.Add(1)
+	}
+}
+
+func (s *NamespaceCounters) Miss() {
+	if s != nil {
+		s.misses.Add(1)
+	}
+}
+
+// Loaded count a call of the loader, failed or not
+func (s *NamespaceCounters) Loaded(err error) {
+	if s == nil {
+		return
+	}
+	s.loads.Add(1)
+	if err != nil {
+		s.loadErrors.Add(1)
+	}
+}
+
+func (s *NamespaceCounters) Reject() {
+	if s != nil {
+		s.rejected.Add(1)
+	}
+}
+
+// Stats return the counters with len as the number of entries
+func (s *NamespaceCounters) Stats(len int64) NamespaceStats {
+	return NamespaceStats{
+		Hits:       s.hits.Load(),
+		Misses:     s.misses.Load(),
+		Loads:      s.loads.Load(),
+		LoadErrors: s.loadErrors.Load(),
+		Rejected:   s.rejected.Load(),
+		Len:        len,
+	}
+}
+
+// NamespaceQuota count the live entries of one generation of a namespace
+type NamespaceQuota struct {
+	gen   atomic.Uint64
+	count atomic.Int64
+	max   atomic.Int64
+}
+
+// Gen return the current generation
+func (q *NamespaceQuota) Gen() uint64 {
+	return q.gen.Load()
+}
+
+// Admit take room for a new entry, false when the quota is reached
+func (q *NamespaceQuota) Admit() bool {
+	for {
+		n, max := q.count.Load(), q.max.Load()
+		if max > 0 && n >= max {
+			return false
+		}
+		if q.count.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// Release give back the room of an entry of generation gen, entries of
+// an older generation were already dropped from the count by Bump
+func (q *NamespaceQuota) Release(gen uint64) {
+	if gen == q.gen.Load() {
+		q.count.Add(-1)
+	}
+}
+
+// Bump start a new generation, leaving the entries of the old one behind
+func (q *NamespaceQuota) Bump() {
+	q.gen.Add(1)
+	q.count.Store(0)
+}
+
+// SetMax set the quota, 0 is no limit
+func (q *NamespaceQuota) SetMax(n int) {
+	q.max.Store(int64(n))
+}
+
+func (q *NamespaceQuota) Len() int64 {
+	return q.count.Load()
+}
+
+// Namespace is a view of a PartitionCache with its own ttl, loader, stats
+// and quota. Its keys live in the shards of the cache, under a hash seeded
+// by the name, and share its capacity and eviction.
+type Namespace[K comparable, V any] struct {
+	noCopy
+	name            string
+	seed            uintptr
+	c               *PartitionCache[K, V]
+	defaultDuration time.Duration
+	caller          func(K) (V, error)
+	quota           NamespaceQuota
+	stats           NamespaceCounters
+}
+
+// Namespace return the view called name, the same one on every call
+func (c *PartitionCache[K, V]) Namespace(name string) *Namespace[K, V] {
+	c.nsMu.Lock()
+	defer c.nsMu.Unlock()
+	if ns, ok := c.namespaces[name]; ok {
+		return ns
+	}
+	if c.namespaces == nil {
+		c.namespaces = make(map[string]*Namespace[K, V])
+	}
+	ns := &Namespace[K, V]{
+		name:            name,
+		seed:            ehash(name),
+		c:               c,
+		defaultDuration: c.defaultDuration,
+	}
+	c.namespaces[name] = ns
+	return ns
+}
+
+// Name return the name of the namespace
+func (ns *Namespace[K, V]) Name() string {
+	return ns.name
+}
+
+// WithCallback set the loader of the namespace, the one of the cache is
+// not used
+func (ns *Namespace[K, V]) WithCallback(call func(K) (V, error)) {
+	ns.caller = call
+}
+
+// WithTTL set the default ttl of the namespace
+func (ns *Namespace[K, V]) WithTTL(dur time.Duration) {
+	ns.defaultDuration = dur
+}
+
+// WithQuota limit the namespace to n entries, 0 is no limit. New keys
+// beyond it are not stored.
+func (ns *Namespace[K, V]) WithQuota(n int) {
+	ns.quota.SetMax(n)
+}
+
+func (ns *Namespace[K, V]) hash(k K) uintptr {
+	return ehash(k) ^ ns.seed
+}
+
+// Get namespace value
+// error maybe not found, timeout
+func (ns *Namespace[K, V]) Get(k K) (r V, err error) {
+	h := ns.hash(k)
+	return ns.c.table.Load().bucket(h).Get(ns.c, ns, k, h)
+}
+
+func (ns *Namespace[K, V]) Set(k K, v V) {
+	ns.SetWithExp(k, v, ns.defaultDuration)
+}
+
+// SetWithExp actively set namespace value
+func (ns *Namespace[K, V]) SetWithExp(k K, v V, dur time.Duration) {
+	h := ns.hash(k)
+	ns.c.table.Load().bucket(h).put(h, ns.c.item(ns, k, v, dur, nil), nil)
+}
+
+// Delete remove k
+func (ns *Namespace[K, V]) Delete(k K) {
+	h := ns.hash(k)
+	ns.c.table.Load().bucket(h).delete(k, h, ns, OpDelete, nil, nil)
+}
+
+// Bump move the namespace to a new generation: every entry written before
+// becomes a miss at once. The janitor and the clock hand reclaim them.
+func (ns *Namespace[K, V]) Bump() {
+	ns.quota.Bump()
+}
+
+// Flush drop every entry of the namespace in O(1), see Bump
+func (ns *Namespace[K, V]) Flush() {
+	ns.Bump()
+}
+
+// Len return the number of entries, approximate while a Flush runs
+func (ns *Namespace[K, V]) Len() int {
+	return int(ns.quota.Len())
+}
+
+// Stats return the counters of the namespace
+func (ns *Namespace[K, V]) Stats() NamespaceStats {
+	return ns.stats.Stats(ns.quota.Len())
+}
+
+// the methods below are no-ops on nil, the cache itself
+
+func (ns *Namespace[K, V]) hit() {
+	if ns != nil {
+		ns.stats.Hit()
+	}
+}
+
+func (ns *Namespace[K, V]) miss() {
+	if ns != nil {
+		ns.stats.Miss()
+	}
+}
+
+func (ns *Namespace[K, V]) loaded(err error) {
+	if ns != nil {
+		ns.stats.Loaded(err)
+	}
+}
+
+func (ns *Namespace[K, V]) gen() uint64 {
+	if ns == nil {
+		return 0
+	}
+	return ns.quota.Gen()
+}
+
+// admit take room for a new entry, counting it as rejected when there is none
+func (ns *Namespace[K, V]) admit() bool {
+	if ns == nil || ns.quota.Admit() {
+		return true
+	}
+	ns.stats.Reject()
+	return false
+}
+
+func (ns *Namespace[K, V]) release(gen uint64) {
+	if ns != nil {
+		ns.quota.Release(gen)
+	}
+}
+
+// owned report whether the entry is k of namespace ns, in its current
+// generation and the current epoch of the cache
+func (i TemplateItem[K, V]) owned(k K, ns *Namespace[K, V], epoch uint64) bool {
+	return i.k == k && i.ns == ns && i.gen == ns.gen() && i.epoch == epoch
+}
+
+// stale report whether the entry was invalidated by InvalidateAll or Bump
+func (i TemplateItem[K, V]) stale(epoch uint64) bool {
+	return i.epoch != epoch || (i.ns != nil && i.gen != i.ns.gen())
+}
+
+func (c *PartitionCache[K, V]) callerOf(ns *Namespace[K, V]) func(K) (V, error) {
+	if ns == nil {
+		return c.caller
+	}
+	return ns.caller
+}
+
+func (c *PartitionCache[K, V]) durationOf(ns *Namespace[K, V]) time.Duration {
+	if ns == nil {
+		return c.defaultDuration
+	}
+	return ns.defaultDuration
+}
+
+// item new entry of k in namespace ns
+func (c *PartitionCache[K, V]) item(ns *Namespace[K, V], k K, v V, dur time.Duration, tags []string) TemplateItem[K, V] {
+	return TemplateItem[K, V]{
+		k:          k,
+		obj:        v,
+		expiration: c.stamps.clock.Now() + int64(dur),
+		duration:   int64(dur),
+		tags:       tags,
+		ns:         ns,
+		gen:        ns.gen(),
+	}
+}
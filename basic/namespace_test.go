@@ -0,0 +1,169 @@
+package basic
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestNamespace(t *testing.T) {
+	Convey("namespaces keep the same key apart", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4))
+		defer cache.Close()
+		a, b := cache.Namespace("a"), cache.Namespace("b")
+		So(cache.Namespace("a"), ShouldEqual, a)
+		cache.Set("k", 0)
+		a.Set("k", 1)
+		b.Set("k", 2)
+		v, err := a.Get("k")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		v, _ = b.Get("k")
+		So(v, ShouldEqual, 2)
+		v, _ = cache.Get("k")
+		So(v, ShouldEqual, 0)
+		a.Delete("k")
+		_, err = a.Get("k")
+		So(err, ShouldEqual, NotFound)
+		v, _ = b.Get("k")
+		So(v, ShouldEqual, 2)
+	})
+
+	Convey("flush drops a namespace at once and leaves the others", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(8))
+		defer cache.Close()
+		a, b := cache.Namespace("a"), cache.Namespace("b")
+		for i := 0; i < 100; i++ {
+			a.Set(i, i)
+			b.Set(i, i)
+		}
+		So(a.Len(), ShouldEqual, 100)
+		a.Flush()
+		So(a.Len(), ShouldEqual, 0)
+		_, err := a.Get(7)
+		So(err, ShouldEqual, NotFound)
+		v, err := b.Get(7)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 7)
+		// the stale entry is replaced, not updated
+		a.Set(7, 70)
+		So(a.Len(), ShouldEqual, 1)
+		v, _ = a.Get(7)
+		So(v, ShouldEqual, 70)
+		cache.Resize(32)
+		v, _ = a.Get(7)
+		So(v, ShouldEqual, 70)
+		_, err = a.Get(8)
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("bump leaves the entries in the shards until they are reclaimed", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(1))
+		defer cache.Close()
+		ns := cache.Namespace("b")
+		for i := 0; i < 5; i++ {
+			ns.Set(i, i)
+		}
+		ns.Bump()
+		b := &cache.table.Load().buckets[0]
+		So(len(b.items), ShouldEqual, 5)
+		_, err := ns.Get(0)
+		So(err, ShouldEqual, NotFound)
+		cache.deleteExpired()
+		So(len(b.items), ShouldEqual, 0)
+		So(ns.Len(), ShouldEqual, 0)
+	})
+
+	Convey("a namespace has its own loader, ttl and stats", t, func() {
+		cache := NewPartitionCache[int, int]()
+		defer cache.Close()
+		cache.WithCallback(func(k int) (int, error) { return k, nil })
+		ns := cache.Namespace("x")
+		ns.WithTTL(time.Millisecond)
+		_, err := ns.Get(1)
+		So(err, ShouldEqual, NotFound)
+		ns.WithCallback(func(k int) (int, error) {
+			if k < 0 {
+				return 0, errors.New("negative")
+			}
+			return k * 10, nil
+		})
+		v, err := ns.Get(2)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 20)
+		_, err = ns.Get(-1)
+		So(err, ShouldEqual, NotFound)
+		v, _ = ns.Get(2)
+		So(v, ShouldEqual, 20)
+		time.Sleep(5 * time.Millisecond)
+		_, err = ns.Get(2)
+		So(err, ShouldEqual, Timeout)
+		s := ns.Stats()
+		So(s.Hits, ShouldEqual, 2)
+		So(s.Misses, ShouldEqual, 3)
+		So(s.Loads, ShouldEqual, 2)
+		So(s.LoadErrors, ShouldEqual, 1)
+		So(s.Len, ShouldEqual, 1)
+		v, _ = cache.Get(2)
+		So(v, ShouldEqual, 2)
+	})
+
+	Convey("the quota drops new keys, not updates", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4))
+		defer cache.Close()
+		ns := cache.Namespace("q")
+		ns.WithQuota(10)
+		for i := 0; i < 20; i++ {
+			ns.Set(fmt.Sprint(i), i)
+		}
+		So(ns.Len(), ShouldEqual, 10)
+		So(ns.Stats().Rejected, ShouldEqual, 10)
+		n := 0
+		for i := 0; i < 20; i++ {
+			if _, err := ns.Get(fmt.Sprint(i)); err == nil {
+				ns.Set(fmt.Sprint(i), -i)
+				n++
+			}
+		}
+		So(n, ShouldEqual, 10)
+		So(ns.Stats().Rejected, ShouldEqual, 10)
+		ns.Flush()
+		ns.Set("new", 1)
+		So(ns.Len(), ShouldEqual, 1)
+	})
+
+	Convey("stale entries are evicted first and stay out of snapshots and tags", t, func() {
+		cache := NewPartitionCache[string, int](WithCapacity(8), WithShards(1))
+		defer cache.Close()
+		ns := cache.Namespace("n")
+		for i := 0; i < 8; i++ {
+			ns.Set(fmt.Sprint(i), i)
+		}
+		for i := 0; i < 8; i++ {
+			ns.Get(fmt.Sprint(i))
+		}
+		ns.Flush()
+		for i := 0; i < 8; i++ {
+			cache.Set(fmt.Sprintf("root%d", i), i)
+		}
+		for i := 0; i < 8; i++ {
+			_, err := cache.Get(fmt.Sprintf("root%d", i))
+			So(err, ShouldBeNil)
+		}
+		ns.Set("live", 1)
+		So(cache.InvalidatePrefix("live"), ShouldEqual, 0)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewPartitionCache[string, int]()
+		defer restored.Close()
+		So(restored.Restore(&buf), ShouldBeNil)
+		_, err := restored.Namespace("n").Get("live")
+		So(err, ShouldEqual, NotFound)
+		_, err = restored.Get("live")
+		So(err, ShouldEqual, NotFound)
+	})
+}
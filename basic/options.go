@@ -0,0 +1,155 @@
+package basic
+
+import "runtime"
+
+// Options holds the construction time settings of a cache.
+type Options struct {
+	// Shards is the number of shards, always a power of two.
+	Shards int
+	// Capacity bounds the unbounded caches with a CLOCK policy, 0 is no limit.
+	Capacity int
+	// Type is the eviction policy of bounded caches.
+	Type Type
+	// Admission puts a TinyLFU filter in front of LRU eviction.
+	Admission bool
+	// WindowRatio is the share of the capacity given to the admission
+	// window of W-TinyLFU, the small FIFO of S3-FIFO or A1in of 2Q,
+	// 0 means the policy default.
+	WindowRatio float64
+	// ProtectedRatio is the share of the main space of W-TinyLFU and SLRU
+	// kept for entries that were hit again, 0 means the policy default.
+	ProtectedRatio float64
+	// LoadBatch is the most keys GetMany hands the bulk loader at once
+	LoadBatch int
+	// PrefixIndex keeps a radix tree of the string keys for InvalidatePrefix
+	PrefixIndex bool
+	// HotKeys is the number of hot keys tracked, 0 turns tracking off
+	HotKeys int
+	// HotKeySample records one Get in HotKeySample for hot key tracking
+	HotKeySample int
+	// HotKeyReplicas is the most hot keys served from a lock-free copy
+	HotKeyReplicas int
+	// Clock is the time source of expirations and of the janitor
+	Clock Clock
+}
+
+// Option configures a cache at construction time.
+type Option func(*Options)
+
+// WithShards set the number of shards, rounded up to a power of two
+func WithShards(n int) Option {
+	return func(o *Options) {
+		o.Shards = n
+	}
+}
+
+// WithCapacity bound the number of entries, entries not used since the
+// last sweep of the CLOCK hand are evicted first
+func WithCapacity(n int) Option {
+	return func(o *Options) {
+		o.Capacity = n
+	}
+}
+
+// WithType set the eviction policy
+func WithType(t Type) Option {
+	return func(o *Options) {
+		o.Type = t
+	}
+}
+
+// WithAdmission only let new entries in over an eviction victim
+// when TinyLFU estimates they are used more often
+func WithAdmission() Option {
+	return func(o *Options) {
+		o.Admission = true
+	}
+}
+
+// WithWindowRatio set the share of the capacity used by the admission window
+func WithWindowRatio(r float64) Option {
+	return func(o *Options) {
+		o.WindowRatio = r
+	}
+}
+
+// WithProtectedRatio set the share of the main space kept for entries hit twice
+func WithProtectedRatio(r float64) Option {
+	return func(o *Options) {
+		o.ProtectedRatio = r
+	}
+}
+
+// WithLoadBatch set the most keys passed to the bulk loader in one call
+func WithLoadBatch(n int) Option {
+	return func(o *Options) {
+		o.LoadBatch = n
+	}
+}
+
+// WithPrefixIndex keep a radix tree of the string keys, so
+// InvalidatePrefix does not have to walk every shard
+func WithPrefixIndex() Option {
+	return func(o *Options) {
+		o.PrefixIndex = true
+	}
+}
+
+// WithHotKeys track the k hottest keys, sampling one Get in sample,
+// 16 when sample is 0. See HotKeyTracker.
+func WithHotKeys(k, sample int) Option {
+	return func(o *Options) {
+		o.HotKeys = k
+		o.HotKeySample = sample
+	}
+}
+
+// WithHotKeyReplicas serve up to n hot keys from a lock-free copy, so
+// their Gets skip the lock of their shard. A key is copied once it alone
+// takes the mean load of a shard. Sampled Gets still take the lock to
+// keep the key in the eviction policy, so the tracker must sample: hot
+// keys are tracked with WithHotKeys(4*n, 16) unless given.
+func WithHotKeyReplicas(n int) Option {
+	return func(o *Options) {
+		o.HotKeyReplicas = n
+	}
+}
+
+// WithClock set the time source of the cache, SystemClock by default
+// and when c is nil. A FakeClock makes expiry deterministic in tests.
+func WithClock(c Clock) Option {
+	return func(o *Options) {
+		if c != nil {
+			o.Clock = c
+		}
+	}
+}
+
+// NewOptions apply opts over the defaults
+func NewOptions(opts ...Option) Options {
+	o := Options{LoadBatch: 100, Clock: SystemClock}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.Shards = ShardCount(o.Shards)
+	if o.HotKeyReplicas > 0 && o.HotKeys == 0 {
+		o.HotKeys = 4 * o.HotKeyReplicas
+	}
+	return o
+}
+
+// ShardCount round n up to a power of two.
+// n <= 0 means the default: 4 shards per P, but never less than InitialSize.
+func ShardCount(n int) int {
+	if n <= 0 {
+		n = 4 * runtime.GOMAXPROCS(0)
+		if n < InitialSize {
+			n = InitialSize
+		}
+	}
+	s := 1
+	for s < n {
+		s <<= 1
+	}
+	return s
+}
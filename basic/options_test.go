@@ -0,0 +1,34 @@
+package basic
+
+import (
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShardCount(t *testing.T) {
+	Convey("shard count is a power of two", t, func() {
+		So(ShardCount(1), ShouldEqual, 1)
+		So(ShardCount(3), ShouldEqual, 4)
+		So(ShardCount(64), ShouldEqual, 64)
+		So(ShardCount(65), ShouldEqual, 128)
+		def := ShardCount(0)
+		So(def&(def-1), ShouldEqual, 0)
+		So(def, ShouldBeGreaterThanOrEqualTo, InitialSize)
+		So(def, ShouldBeGreaterThanOrEqualTo, runtime.GOMAXPROCS(0))
+		So(NewOptions(WithShards(100)).Shards, ShouldEqual, 128)
+	})
+}
+
+func TestWithClock(t *testing.T) {
+	Convey("a nil clock keeps the system clock", t, func() {
+		So(NewOptions(WithClock(nil)).Clock, ShouldResemble, SystemClock)
+		cache := NewPartitionCache[string, int](WithClock(nil))
+		defer cache.Close()
+		cache.Set("a", 1)
+		v, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,90 @@
+package basic
+
+import "time"
+
+// EntryInfo is an entry with its metadata, see GetEntry
+type EntryInfo[K comparable, V any] struct {
+	Key   K
+	Value V
+	// Expiration is zero when the entry never expires
+	Expiration time.Time
+	// Duration is the ttl the entry was set with
+	Duration time.Duration
+	Created  time.Time
+	// Hits counts the reads through Get and GetMany
+	Hits uint64
+	// Stale reports an expired entry that is still cached, Get returns
+	// it with Timeout
+	Stale bool
+}
+
+// NewEntryInfo fill an EntryInfo from the fields of a cached entry, Stale
+// against clock
+func NewEntryInfo[K comparable, V any](clock Clock, k K, v V, expiration, duration, created int64, hits uint64) EntryInfo[K, V] {
+	e := EntryInfo[K, V]{
+		Key:      k,
+		Value:    v,
+		Duration: time.Duration(duration),
+		Created:  time.Unix(0, created),
+		Hits:     hits,
+	}
+	if expiration != 0 {
+		e.Expiration = time.Unix(0, expiration)
+		e.Stale = clock.Now() > expiration
+	}
+	return e
+}
+
+// Peek read k without side effects: no clock bit, no hit, no loader and
+// no refresh
+// error maybe not found, timeout
+func (c *PartitionCache[K, V]) Peek(k K) (r V, err error) {
+	item, ok := c.peek(k)
+	if !ok {
+		return r, NotFound
+	}
+	if item.expiredAt(c.stamps.clock.Now()) {
+		return item.obj, Timeout
+	}
+	return item.obj, nil
+}
+
+// GetEntry return k with its metadata, without side effects like Peek.
+// An expired entry is returned as Stale, error is only not found.
+func (c *PartitionCache[K, V]) GetEntry(k K) (EntryInfo[K, V], error) {
+	item, ok := c.peek(k)
+	if !ok {
+		return EntryInfo[K, V]{}, NotFound
+	}
+	return NewEntryInfo(c.stamps.clock, item.k, item.obj, item.expiration, item.duration, item.created, item.hits.Load()), nil
+}
+
+// Touch give k a new ttl without rewriting its value, it reports whether
+// k was there. Expired entries are not revived.
+func (c *PartitionCache[K, V]) Touch(k K, ttl time.Duration) bool {
+	h := ehash(k)
+	w := c.wal.Load()
+	b := c.table.Load().bucket(h).lock(h)
+	i, ok := b.items[h]
+	if !ok || !i.owned(k, nil, b.stamps.epoch.Load()) || i.expiredAt(b.now()) {
+		b.mu.Unlock()
+		return false
+	}
+	i.expiration = b.now() + int64(ttl)
+	i.duration = int64(ttl)
+	b.items[h] = i
+	b.replicas.Store(k, i)
+	seq := w.Append(OpSet, &Entry[K, V]{Key: k, Value: i.obj, Expiration: i.expiration, Duration: i.duration, Tags: i.tags})
+	b.mu.Unlock()
+	w.Wait(seq)
+	return true
+}
+
+func (c *PartitionCache[K, V]) peek(k K) (TemplateItem[K, V], bool) {
+	h := ehash(k)
+	b := c.table.Load().bucket(h).rlock(h)
+	item, ok := b.items[h]
+	epoch := b.stamps.epoch.Load()
+	b.mu.RUnlock()
+	return item, ok && item.owned(k, nil, epoch)
+}
@@ -0,0 +1,72 @@
+package basic
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPeek(t *testing.T) {
+	Convey("peek neither loads nor counts a hit", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		loads := 0
+		cache.WithCallback(func(k string) (int, error) {
+			loads++
+			return 1, nil
+		})
+		_, err := cache.Peek("a")
+		So(err, ShouldEqual, NotFound)
+		So(loads, ShouldEqual, 0)
+		cache.Get("a")
+		cache.Get("a")
+		v, err := cache.Peek("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		e, err := cache.GetEntry("a")
+		So(err, ShouldBeNil)
+		So(e.Hits, ShouldEqual, 1)
+		So(e.Stale, ShouldBeFalse)
+		So(e.Duration, ShouldEqual, 10*time.Second)
+		So(e.Expiration.Sub(e.Created), ShouldBeBetweenOrEqual, 10*time.Second-time.Millisecond, 10*time.Second)
+		So(loads, ShouldEqual, 1)
+	})
+
+	Convey("get entry reports stale entries, touch extends live ones", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		cache.SetWithExp("a", 1, time.Millisecond)
+		cache.SetWithExp("b", 2, time.Millisecond)
+		_, version, _ := cache.GetVersion("a")
+		So(cache.Touch("a", time.Hour), ShouldBeTrue)
+		time.Sleep(2 * time.Millisecond)
+		v, err := cache.Peek("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		_, next, _ := cache.GetVersion("a")
+		So(next, ShouldEqual, version)
+		e, err := cache.GetEntry("b")
+		So(err, ShouldBeNil)
+		So(e.Stale, ShouldBeTrue)
+		_, err = cache.Peek("b")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Touch("b", time.Hour), ShouldBeFalse)
+		So(cache.Touch("missing", time.Hour), ShouldBeFalse)
+		e, _ = cache.GetEntry("a")
+		So(e.Duration, ShouldEqual, time.Hour)
+	})
+
+	Convey("hits survive updates and resize", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(2))
+		defer cache.Close()
+		cache.Set(1, 1)
+		cache.Get(1)
+		cache.Set(1, 2)
+		cache.Get(1)
+		cache.Resize(16)
+		e, _ := cache.GetEntry(1)
+		So(e.Hits, ShouldEqual, 2)
+		So(e.Value, ShouldEqual, 2)
+	})
+}
@@ -0,0 +1,138 @@
+package basic
+
+import (
+	"sort"
+	"strings"
+)
+
+// radixNode is a node of a radix tree: the edge to it is labelled prefix,
+// leaf marks that the path from the root to it is a stored string
+type radixNode struct {
+	prefix   string
+	leaf     bool
+	children []*radixNode
+}
+
+// child find the child whose edge starts with b, or the index it belongs at
+func (n *radixNode) child(b byte) (int, *radixNode) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].prefix[0] >= b
+	})
+	if i < len(n.children) && n.children[i].prefix[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+// merge fold the only child of n into n
+func (n *radixNode) merge() {
+	c := n.children[0]
+	n.prefix += c.prefix
+	n.leaf = c.leaf
+	n.children = c.children
+}
+
+// radixTree is a set of strings that can be walked by prefix
+type radixTree struct {
+	root radixNode
+	size int
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// insert add s and report whether it was new
+func (t *radixTree) insert(s string) bool {
+	n := &t.root
+	for s != "" {
+		i, c := n.child(s[0])
+		if c == nil {
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = &radixNode{prefix: s, leaf: true}
+			t.size++
+			return true
+		}
+		l := commonPrefix(c.prefix, s)
+		if l < len(c.prefix) {
+			split := &radixNode{prefix: c.prefix[:l], children: []*radixNode{c}}
+			c.prefix = c.prefix[l:]
+			n.children[i] = split
+			c = split
+		}
+		n = c
+		s = s[l:]
+	}
+	if n.leaf {
+		return false
+	}
+	n.leaf = true
+	t.size++
+	return true
+}
+
+// remove delete s and report whether it was there
+func (t *radixTree) remove(s string) bool {
+	var parent *radixNode
+	idx := 0
+	n := &t.root
+	for s != "" {
+		i, c := n.child(s[0])
+		if c == nil || !strings.HasPrefix(s, c.prefix) {
+			return false
+		}
+		parent, idx, n = n, i, c
+		s = s[len(c.prefix):]
+	}
+	if !n.leaf {
+		return false
+	}
+	n.leaf = false
+	t.size--
+	switch {
+	case parent == nil:
+	case len(n.children) == 0:
+		parent.children = append(parent.children[:idx], parent.children[idx+1:]...)
+		if parent != &t.root && !parent.leaf && len(parent.children) == 1 {
+			parent.merge()
+		}
+	case len(n.children) == 1:
+		n.merge()
+	}
+	return true
+}
+
+// walk call fn with every string that starts with p
+func (t *radixTree) walk(p string, fn func(string)) {
+	n, path := &t.root, ""
+	for p != "" {
+		_, c := n.child(p[0])
+		if c == nil {
+			return
+		}
+		l := commonPrefix(c.prefix, p)
+		if l == len(p) {
+			collect(c, path+c.prefix, fn)
+			return
+		}
+		if l < len(c.prefix) {
+			return
+		}
+		n, path, p = c, path+c.prefix, p[l:]
+	}
+	collect(n, path, fn)
+}
+
+func collect(n *radixNode, path string, fn func(string)) {
+	if n.leaf {
+		fn(path)
+	}
+	for _, c := range n.children {
+		collect(c, path+c.prefix, fn)
+	}
+}
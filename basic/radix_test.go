@@ -0,0 +1,77 @@
+package basic
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRadixTree(t *testing.T) {
+	Convey("radix tree finds keys by prefix", t, func() {
+		var tree radixTree
+		for _, s := range []string{"tenant:1:a", "tenant:1:b", "tenant:12:a", "tenant:2:a", "tenant", "t"} {
+			So(tree.insert(s), ShouldBeTrue)
+		}
+		So(tree.insert("tenant:1:a"), ShouldBeFalse)
+		So(tree.size, ShouldEqual, 6)
+		walk := func(p string) []string {
+			var r []string
+			tree.walk(p, func(s string) { r = append(r, s) })
+			sort.Strings(r)
+			return r
+		}
+		So(walk("tenant:1:"), ShouldResemble, []string{"tenant:1:a", "tenant:1:b"})
+		So(walk("tenant:1"), ShouldResemble, []string{"tenant:12:a", "tenant:1:a", "tenant:1:b"})
+		So(walk("tenant:3"), ShouldBeEmpty)
+		So(walk("x"), ShouldBeEmpty)
+		So(walk(""), ShouldHaveLength, 6)
+
+		So(tree.remove("tenant:1:b"), ShouldBeTrue)
+		So(tree.remove("tenant:1:b"), ShouldBeFalse)
+		So(tree.remove("tenant:"), ShouldBeFalse)
+		So(tree.remove("tenant"), ShouldBeTrue)
+		So(walk("tenant"), ShouldResemble, []string{"tenant:12:a", "tenant:1:a", "tenant:2:a"})
+	})
+
+	Convey("radix tree matches a map under random inserts and removes", t, func() {
+		var tree radixTree
+		set := map[string]bool{}
+		r := rand.New(rand.NewSource(1))
+		key := func() string {
+			b := make([]byte, 1+r.Intn(5))
+			for i := range b {
+				b[i] = "abc"[r.Intn(3)]
+			}
+			return string(b)
+		}
+		for i := 0; i < 5000; i++ {
+			k := key()
+			if r.Intn(2) == 0 {
+				So(tree.insert(k), ShouldEqual, !set[k])
+				set[k] = true
+			} else {
+				So(tree.remove(k), ShouldEqual, set[k])
+				delete(set, k)
+			}
+		}
+		So(tree.size, ShouldEqual, len(set))
+		for _, p := range []string{"", "a", "ab", "cab"} {
+			n := 0
+			tree.walk(p, func(s string) {
+				So(set[s], ShouldBeTrue)
+				So(strings.HasPrefix(s, p), ShouldBeTrue)
+				n++
+			})
+			want := 0
+			for s := range set {
+				if strings.HasPrefix(s, p) {
+					want++
+				}
+			}
+			So(n, ShouldEqual, want)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package basic
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// HotReplicas is a lock-free read copy of the hottest keys of a cache.
+// The set of keys is an immutable map swapped by Follow; each key has a
+// slot holding a copy of its entry. Writers update the slot under the
+// write lock of the shard, readers fill an empty slot under the read
+// lock, so a slot never holds an entry older than the shard.
+// Invalidation by epoch is left to the reader, who checks the copy like
+// an entry of the shard.
+type HotReplicas[K comparable, T any] struct {
+	mu   sync.Mutex
+	n    int
+	keys atomic.Pointer[map[K]*atomic.Pointer[T]]
+}
+
+// NewHotReplicas new replicas of at most n keys, nil when n is 0
+func NewHotReplicas[K comparable, T any](n int) *HotReplicas[K, T] {
+	if n <= 0 {
+		return nil
+	}
+	r := &HotReplicas[K, T]{n: n}
+	r.keys.Store(&map[K]*atomic.Pointer[T]{})
+	return r
+}
+
+func (r *HotReplicas[K, T]) slot(k K) *atomic.Pointer[T] {
+	if r == nil {
+		return nil
+	}
+	return (*r.keys.Load())[k]
+}
+
+// Load return the copy of k, if k is replicated and its slot is filled
+func (r *HotReplicas[K, T]) Load(k K) (*T, bool) {
+	s := r.slot(k)
+	if s == nil {
+		return nil, false
+	}
+	v := s.Load()
+	return v, v != nil
+}
+
+// Fill copy v into the empty slot of k, the read lock of its shard
+// must be held
+func (r *HotReplicas[K, T]) Fill(k K, v T) {
+	if s := r.slot(k); s != nil && s.Load() == nil {
+		s.CompareAndSwap(nil, &v)
+	}
+}
+
+// Store copy v into the slot of k, the write lock of its shard must be
+// held
+func (r *HotReplicas[K, T]) Store(k K, v T) {
+	if s := r.slot(k); s != nil {
+		s.Store(&v)
+	}
+}
+
+// Drop empty the slot of k, the write lock of its shard must be held
+func (r *HotReplicas[K, T]) Drop(k K) {
+	if s := r.slot(k); s != nil {
+		s.Store(nil)
+	}
+}
+
+// Follow replicate the keys of t that alone take at least the mean load
+// of a shard, at most n of them. Keys staying hot keep their slot, new
+// ones start empty and are filled by the next Get taking the lock.
+func (r *HotReplicas[K, T]) Follow(t *HotKeyTracker[K]) {
+	if r == nil {
+		return
+	}
+	s := t.Stats()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := *r.keys.Load()
+	keys := make(map[K]*atomic.Pointer[T], r.n)
+	for _, k := range t.Top(r.n) {
+		if (k.Count-k.Error)*uint64(len(s.Shards)) < s.Sampled {
+			continue
+		}
+		if keys[k.Key] = old[k.Key]; keys[k.Key] == nil {
+			keys[k.Key] = new(atomic.Pointer[T])
+		}
+	}
+	r.keys.Store(&keys)
+}
+
+// Keys return the replicated keys
+func (r *HotReplicas[K, T]) Keys() []K {
+	if r == nil {
+		return nil
+	}
+	m := *r.keys.Load()
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,127 @@
+package basic
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHotReplicas(t *testing.T) {
+	Convey("only keys taking a shard's mean load are replicated", t, func() {
+		tr := NewHotKeyTracker[string](8, 1)
+		r := NewHotReplicas[string, int](2)
+		for i := 0; i < 100; i++ {
+			tr.Observe("a", 0, 4)
+			tr.Observe("b", 1, 4)
+			if i%4 == 0 {
+				tr.Observe("c", 2, 4)
+			}
+		}
+		r.Follow(tr)
+		So(r.Keys(), ShouldHaveLength, 2)
+		r.Store("c", 3)
+		_, ok := r.Load("c")
+		So(ok, ShouldBeFalse)
+		_, ok = r.Load("a")
+		So(ok, ShouldBeFalse)
+		r.Fill("a", 1)
+		r.Fill("a", 2)
+		v, ok := r.Load("a")
+		So(ok, ShouldBeTrue)
+		So(*v, ShouldEqual, 1)
+		r.Store("a", 2)
+		v, _ = r.Load("a")
+		So(*v, ShouldEqual, 2)
+		// a key staying hot keeps its copy
+		r.Follow(tr)
+		v, _ = r.Load("a")
+		So(*v, ShouldEqual, 2)
+		r.Drop("a")
+		_, ok = r.Load("a")
+		So(ok, ShouldBeFalse)
+		So(NewHotReplicas[string, int](0), ShouldBeNil)
+	})
+
+	Convey("replicated keys stay coherent with the shards", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4), WithHotKeyReplicas(2), WithHotKeys(8, 2))
+		defer cache.Close()
+		cache.Set("hot", 1)
+		cache.Set("cold", 1)
+		for i := 0; i < 200; i++ {
+			cache.Get("hot")
+		}
+		cache.Get("cold")
+		cache.deleteExpired()
+		So(cache.Replicated(), ShouldResemble, []string{"hot"})
+		read := func(want int, err error) {
+			for i := 0; i < 50; i++ {
+				v, e := cache.Get("hot")
+				So(e, ShouldEqual, err)
+				So(v, ShouldEqual, want)
+			}
+		}
+		read(1, nil)
+		_, ok := cache.replicas.Load("hot")
+		So(ok, ShouldBeTrue)
+		cache.Set("hot", 2)
+		read(2, nil)
+		cache.Compute("hot", func(old int, exists bool) (int, Action) { return old + 1, Update })
+		read(3, nil)
+		cache.Resize(8)
+		read(3, nil)
+		cache.Delete("hot")
+		read(0, NotFound)
+		cache.Set("hot", 4)
+		read(4, nil)
+		cache.InvalidateAll()
+		read(0, NotFound)
+		cache.SetWithExp("hot", 5, 100*time.Millisecond)
+		read(5, nil)
+		time.Sleep(110 * time.Millisecond)
+		read(5, Timeout)
+		e, _ := cache.GetEntry("hot")
+		So(e.Hits, ShouldEqual, 100)
+	})
+
+	Convey("readers never see a value older than the last write", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(2), WithHotKeyReplicas(1), WithHotKeys(4, 2))
+		defer cache.Close()
+		cache.Set(1, 0)
+		for i := 0; i < 100; i++ {
+			cache.Get(1)
+		}
+		cache.deleteExpired()
+		So(cache.Replicated(), ShouldResemble, []int{1})
+		var mu sync.RWMutex
+		written := 0
+		var wg sync.WaitGroup
+		stale := make(chan int, 1)
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					mu.RLock()
+					low := written
+					mu.RUnlock()
+					if v, _ := cache.Get(1); v < low {
+						select {
+						case stale <- v:
+						default:
+						}
+					}
+				}
+			}()
+		}
+		for i := 1; i <= 500; i++ {
+			cache.Set(1, i)
+			mu.Lock()
+			written = i
+			mu.Unlock()
+		}
+		wg.Wait()
+		So(len(stale), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,213 @@
+package basic
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sieveNode is an entry of the SieveCache queue
+type sieveNode[K comparable, V any] struct {
+	k          K
+	obj        V
+	expiration int64
+	duration   int64
+	visited    atomic.Bool
+	prev       *sieveNode[K, V]
+	next       *sieveNode[K, V]
+}
+
+// Expired is expired data by the system clock, the cache checks its
+// entries against the clock of its options
+func (n *sieveNode[K, V]) Expired() bool {
+	if n.expiration == 0 {
+		return false
+	}
+	return time.Now().UnixNano() > n.expiration
+}
+
+// expiredAt is Expired at now
+func (n *sieveNode[K, V]) expiredAt(now int64) bool {
+	return n.expiration != 0 && now > n.expiration
+}
+
+// SieveCache is a bounded cache with the SIEVE policy: one FIFO queue, a
+// visited bit per entry and a hand walking from the oldest entry to the
+// newest. The hand clears visited bits and evicts the first entry it finds
+// unvisited. Entries never move in the queue, a hit only sets the bit, so
+// Get needs the read lock only.
+type SieveCache[K comparable, V any] struct {
+	noCopy
+	defaultDuration time.Duration
+	mu              sync.RWMutex
+	items           map[K]*sieveNode[K, V]
+	head            *sieveNode[K, V]
+	tail            *sieveNode[K, V]
+	hand            *sieveNode[K, V]
+	size            int
+	randfunc        func(int64, int64) bool
+	caller          func(K) (V, error)
+	codec           Codec[K, V]
+	clock           Clock
+}
+
+// NewSieveCache new cache holding at most size entries. Of opts only
+// WithClock applies, the size is the capacity.
+func NewSieveCache[K comparable, V any](size int, opts ...Option) *SieveCache[K, V] {
+	o := NewOptions(opts...)
+	if size < 1 {
+		size = 1
+	}
+	return &SieveCache[K, V]{
+		items:           make(map[K]*sieveNode[K, V], size),
+		size:            size,
+		defaultDuration: 10 * time.Second,
+		randfunc:        randfunc,
+		clock:           o.Clock,
+	}
+}
+
+// WithCallback set callback
+func (c *SieveCache[K, V]) WithCallback(call func(K) (V, error)) {
+	c.caller = call
+}
+
+// WithRandfunc set rand func
+func (c *SieveCache[K, V]) WithRandfunc(call func(int64, int64) bool) {
+	c.randfunc = call
+}
+
+// Get SieveCache value
+// error maybe not found, timeout
+func (c *SieveCache[K, V]) Get(k K) (r V, err error) {
+	c.mu.RLock()
+	n, ok := c.items[k]
+	if !ok {
+		c.mu.RUnlock()
+		if c.caller == nil {
+			return r, NotFound
+		}
+		v, err := c.caller(k)
+		if err != nil {
+			return r, NotFound
+		}
+		c.SetWithExp(k, v, c.defaultDuration)
+		return v, nil
+	}
+	n.visited.Store(true)
+	obj, expiration, duration := n.obj, n.expiration, n.duration
+	expired := n.expiredAt(c.clock.Now())
+	c.mu.RUnlock()
+	if expired {
+		c.refresh(k, expiration, duration)
+		return obj, Timeout
+	}
+	c.refresh(k, expiration, duration)
+	return obj, nil
+}
+
+// Set set SieveCache
+func (c *SieveCache[K, V]) Set(k K, v V) {
+	c.SetWithExp(k, v, c.defaultDuration)
+}
+
+// SetWithExp actively set SieveCache value
+func (c *SieveCache[K, V]) SetWithExp(k K, v V, dur time.Duration) {
+	c.set(k, v, c.clock.Now()+int64(dur), int64(dur))
+}
+
+// set store v with an absolute expiration
+func (c *SieveCache[K, V]) set(k K, v V, expiration, duration int64) {
+	c.mu.Lock()
+	if n, ok := c.items[k]; ok {
+		n.obj = v
+		n.expiration = expiration
+		n.duration = duration
+		c.mu.Unlock()
+		return
+	}
+	if len(c.items) >= c.size {
+		c.evict()
+	}
+	n := &sieveNode[K, V]{
+		k:          k,
+		obj:        v,
+		expiration: expiration,
+		duration:   duration,
+	}
+	c.push(n)
+	c.items[k] = n
+	c.mu.Unlock()
+}
+
+// push add n at the head of the queue
+func (c *SieveCache[K, V]) push(n *sieveNode[K, V]) {
+	n.next = c.head
+	if c.head != nil {
+		c.head.prev = n
+	}
+	c.head = n
+	if c.tail == nil {
+		c.tail = n
+	}
+}
+
+// unlink take n out of the queue, moving the hand past it
+func (c *SieveCache[K, V]) unlink(n *sieveNode[K, V]) {
+	if c.hand == n {
+		c.hand = n.prev
+	}
+	if n.prev != nil {
+		n.prev.next = n.next
+	} else {
+		c.head = n.next
+	}
+	if n.next != nil {
+		n.next.prev = n.prev
+	} else {
+		c.tail = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
+
+// evict walk the hand from the tail towards the head, clearing visited
+// bits, and drop the first entry that was not visited or is expired.
+func (c *SieveCache[K, V]) evict() {
+	now := c.clock.Now()
+	n := c.hand
+	if n == nil {
+		n = c.tail
+	}
+	for n != nil {
+		if !n.visited.Load() || n.expiredAt(now) {
+			break
+		}
+		n.visited.Store(false)
+		n = n.prev
+		if n == nil {
+			n = c.tail
+		}
+	}
+	if n == nil {
+		return
+	}
+	c.hand = n
+	c.unlink(n)
+	delete(c.items, n.k)
+}
+
+func (c *SieveCache[K, V]) refresh(k K, expiration, duration int64) {
+	if c.caller == nil {
+		return
+	}
+	t := expiration - c.clock.Now()
+	if t > 0 && t*100/duration < 30 {
+		if c.randfunc != nil && !c.randfunc(t, duration) {
+			return
+		}
+		v, err := c.caller(k)
+		if err == nil {
+			c.SetWithExp(k, v, c.defaultDuration)
+		}
+	}
+}
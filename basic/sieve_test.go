@@ -0,0 +1,68 @@
+package basic
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSieveCache(t *testing.T) {
+	cache := NewSieveCache[string, []byte](defaultSize)
+	cache.WithCallback(getmessage2)
+
+	Convey(fmt.Sprintf("key %v expect %v", "123", "value_123"), t, func() {
+		key := "123"
+		value, err := cache.Get(key)
+		So(err, ShouldResemble, nil)
+		So(value, ShouldResemble, message2)
+	})
+
+	Convey("the hand spares visited entries", t, func() {
+		cache := NewSieveCache[int, int](3)
+		for i := 0; i < 3; i++ {
+			cache.Set(i, i)
+		}
+		cache.Get(0)
+		cache.Set(3, 3)
+		_, err := cache.Get(1)
+		So(err, ShouldEqual, NotFound)
+		_, err = cache.Get(0)
+		So(err, ShouldBeNil)
+		// the hand rests at 2, the next victim is 2 since 3 is newer
+		cache.Set(4, 4)
+		_, err = cache.Get(2)
+		So(err, ShouldEqual, NotFound)
+		So(len(cache.items), ShouldEqual, 3)
+	})
+
+	Convey("values are replaced in place", t, func() {
+		cache := NewSieveCache[string, int](2)
+		cache.Set("a", 1)
+		cache.Set("a", 2)
+		v, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 2)
+		So(len(cache.items), ShouldEqual, 1)
+	})
+}
+
+func BenchmarkReadFromSieveCache(b *testing.B) {
+	cache := NewSieveCache[string, []byte](defaultSize)
+	cache.WithCallback(getmessage2)
+	for i := 0; i < defaultSize; i++ {
+		cache.SetWithExp(strconv.Itoa(i), message2, 100*time.Second)
+	}
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		b.ReportAllocs()
+
+		for pb.Next() {
+			cache.Get(strconv.Itoa(rand.Intn(2 * defaultSize)))
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package basic
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -10,9 +11,11 @@ type Item struct {
 	expiration int64
 	duration   int64
 	color      Color
+	slot       int
 }
 
-// Expired is expired data
+// Expired is expired data by the system clock, the cache checks its
+// entries against the clock of its options
 func (i Item) Expired() bool {
 	if i.expiration == 0 {
 		return false
@@ -20,7 +23,12 @@ func (i Item) Expired() bool {
 	return time.Now().UnixNano() > i.expiration
 }
 
-// Disuse is disuse data
+// expiredAt is Expired at now
+func (i Item) expiredAt(now int64) bool {
+	return i.expiration != 0 && now > i.expiration
+}
+
+// Disuse is disuse data: not used since the clock hand last passed it
 func (i Item) Disuse() bool {
 	if i.color != black {
 		return false
@@ -37,18 +45,24 @@ type SimpleCache struct {
 	randfunc        func(int64, int64) bool
 	caller          func(string) (interface{}, error)
 	size            uint32
-	// order Order
+	capacity        int
+	order           clockRing[string]
+	clock           Clock
+	codec           Codec[string, any]
 }
 
 func (c *SimpleCache) clean() {
 }
 
-// NewSimpleCache new cache
-func NewSimpleCache() *SimpleCache {
+// NewSimpleCache new cache, unbounded unless WithCapacity is given
+func NewSimpleCache(opts ...Option) *SimpleCache {
+	o := NewOptions(opts...)
 	return &SimpleCache{
 		items:           make(map[string]Item),
 		defaultDuration: 10 * time.Second,
 		randfunc:        randfunc,
+		capacity:        o.Capacity,
+		clock:           o.Clock,
 	}
 }
 
@@ -67,11 +81,11 @@ func (c *SimpleCache) WithRandfunc(call func(int64, int64) bool) {
 func (c *SimpleCache) Get(k string) (r any, err error) {
 	c.mu.RLock()
 	v, ok := c.items[k]
+	c.mu.RUnlock()
 	if !ok {
 		if c.caller == nil {
 			return nil, NotFound
 		}
-		c.mu.RUnlock()
 		v, err := c.caller(k)
 		if err != nil {
 			return nil, NotFound
@@ -79,19 +93,27 @@ func (c *SimpleCache) Get(k string) (r any, err error) {
 		c.SetWithExp(k, v, c.defaultDuration)
 		return v, nil
 	}
-	c.mu.RUnlock()
-	if v.Expired() {
-		c.refresh(k, v)
-		return v.obj, Timeout
-	}
 	if v.Disuse() {
+		c.use(k)
+	}
+	if v.expiredAt(c.clock.Now()) {
 		c.refresh(k, v)
-		return v.obj, Disuse
+		return v.obj, Timeout
 	}
 	c.refresh(k, v)
 	return v.obj, nil
 }
 
+// use color k white, so the clock hand passes it once more
+func (c *SimpleCache) use(k string) {
+	c.mu.Lock()
+	if i, ok := c.items[k]; ok {
+		i.color = white
+		c.items[k] = i
+	}
+	c.mu.Unlock()
+}
+
 // Set set SimpleCache
 func (c *SimpleCache) Set(k string, v any) {
 	c.SetWithExp(k, v, c.defaultDuration)
@@ -99,31 +121,65 @@ func (c *SimpleCache) Set(k string, v any) {
 
 // SetWithExp actively set SimpleCache value
 func (c *SimpleCache) SetWithExp(k string, v any, dur time.Duration) {
+	c.set(k, v, c.clock.Now()+int64(dur), int64(dur))
+}
+
+// set store v with an absolute expiration
+func (c *SimpleCache) set(k string, v any, expiration, duration int64) {
 	c.mu.Lock()
 	i, ok := c.items[k]
 	if ok {
 		i.obj = v
-		i.expiration = time.Now().Add(dur).UnixNano()
-		i.duration = int64(dur)
-		// c.items[k] = i
+		i.expiration = expiration
+		i.duration = duration
+		c.items[k] = i
 		c.mu.Unlock()
 		return
 	}
-	c.items[k] = Item{
+	c.insert(k, Item{
 		obj:        v,
-		expiration: time.Now().Add(dur).UnixNano(),
-		duration:   int64(dur),
-		color:      black,
-	}
+		expiration: expiration,
+		duration:   duration,
+	})
 	c.mu.Unlock()
 }
 
+// insert add a new entry, in bounded mode it takes a slot of the clock
+// ring and starts black. The write lock must be held.
+func (c *SimpleCache) insert(k string, item Item) {
+	if c.capacity == 0 {
+		item.color = white
+		c.items[k] = item
+		return
+	}
+	item.color = black
+	if c.order.len() < c.capacity {
+		item.slot = c.order.add(k)
+		c.items[k] = item
+		return
+	}
+	now := c.clock.Now()
+	slot := c.order.sweep(func(k string) bool {
+		i := c.items[k]
+		if i.Disuse() || i.expiredAt(now) {
+			return false
+		}
+		i.color = black
+		c.items[k] = i
+		return true
+	})
+	delete(c.items, c.order.keys[slot])
+	c.order.set(slot, k)
+	item.slot = slot
+	c.items[k] = item
+}
+
 func (c *SimpleCache) refresh(k string, i any) {
 	if c.caller == nil {
 		return
 	}
 	item := i.(Item)
-	t := item.expiration - time.Now().UnixNano()
+	t := item.expiration - c.clock.Now()
 	if t > 0 && t*100/item.duration < 30 {
 		if c.randfunc != nil && !c.randfunc(t, item.duration) {
 			return
@@ -135,17 +191,10 @@ func (c *SimpleCache) refresh(k string, i any) {
 	}
 }
 
-// Load load keys avoid concurrent large traffic penetration
+// Load load keys avoid concurrent large traffic penetration,
+// see Warm for the concurrency, rate limit and failures
 func (c *SimpleCache) Load(ks []string) {
-	if c.caller == nil {
-		return
-	}
-	for _, k := range ks {
-		v, err := c.caller(k)
-		if err == nil {
-			c.SetWithExp(k, v, c.defaultDuration)
-		}
-	}
+	c.Warm(context.Background(), ks)
 }
 
 func (c *SimpleCache) deleteExpired() {
@@ -1,7 +1,9 @@
 package basic
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,9 +13,18 @@ type TemplateItem[K comparable, V any] struct {
 	expiration int64
 	duration   int64
 	color      Color
+	slot       int
+	tags       []string
+	ns         *Namespace[K, V]
+	gen        uint64
+	epoch      uint64
+	version    uint64
+	created    int64
+	hits       *atomic.Uint64
 }
 
-// Expired is expired data
+// Expired is expired data by the system clock, a cache checks its entries
+// against the clock of its options
 func (i TemplateItem[K, V]) Expired() bool {
 	if i.expiration == 0 {
 		return false
@@ -21,7 +32,7 @@ func (i TemplateItem[K, V]) Expired() bool {
 	return time.Now().UnixNano() > i.expiration
 }
 
-// Disuse is disuse data
+// Disuse is disuse data: not used since the clock hand last passed it
 func (i TemplateItem[K, V]) Disuse() bool {
 	if i.color != black {
 		return false
@@ -38,18 +49,24 @@ type TemplateCache[K comparable, V any] struct {
 	randfunc        func(int64, int64) bool
 	caller          func(K) (V, error)
 	size            uint32
-	// order Order
+	capacity        int
+	order           clockRing[K]
+	codec           Codec[K, V]
+	clock           Clock
 }
 
 func (c *TemplateCache[K, V]) clean() {
 }
 
-// NewTemplateCache new cache
-func NewTemplateCache[K comparable, V any]() *TemplateCache[K, V] {
+// NewTemplateCache new cache, unbounded unless WithCapacity is given
+func NewTemplateCache[K comparable, V any](opts ...Option) *TemplateCache[K, V] {
+	o := NewOptions(opts...)
 	return &TemplateCache[K, V]{
 		items:           make(map[K]TemplateItem[K, V]),
 		defaultDuration: 10 * time.Second,
 		randfunc:        randfunc,
+		capacity:        o.Capacity,
+		clock:           o.Clock,
 	}
 }
 
@@ -68,11 +85,11 @@ func (c *TemplateCache[K, V]) WithRandfunc(call func(int64, int64) bool) {
 func (c *TemplateCache[K, V]) Get(k K) (r V, err error) {
 	c.mu.RLock()
 	item, ok := c.items[k]
+	c.mu.RUnlock()
 	if !ok {
 		if c.caller == nil {
 			return r, NotFound
 		}
-		c.mu.RUnlock()
 		v, err := c.caller(k)
 		if err != nil {
 			return r, NotFound
@@ -80,19 +97,27 @@ func (c *TemplateCache[K, V]) Get(k K) (r V, err error) {
 		c.SetWithExp(k, v, c.defaultDuration)
 		return v, nil
 	}
-	c.mu.RUnlock()
-	if item.Expired() {
-		c.refresh(k, item)
-		return item.obj, Timeout
-	}
 	if item.Disuse() {
+		c.use(k)
+	}
+	if item.expiredAt(c.clock.Now()) {
 		c.refresh(k, item)
-		return item.obj, Disuse
+		return item.obj, Timeout
 	}
 	c.refresh(k, item)
 	return item.obj, nil
 }
 
+// use color k white, so the clock hand passes it once more
+func (c *TemplateCache[K, V]) use(k K) {
+	c.mu.Lock()
+	if i, ok := c.items[k]; ok {
+		i.color = white
+		c.items[k] = i
+	}
+	c.mu.Unlock()
+}
+
 // Set set TemplateCache
 func (c *TemplateCache[K, V]) Set(k K, v V) {
 	c.SetWithExp(k, v, c.defaultDuration)
@@ -100,30 +125,64 @@ func (c *TemplateCache[K, V]) Set(k K, v V) {
 
 // SetWithExp actively set TemplateCache value
 func (c *TemplateCache[K, V]) SetWithExp(k K, v V, dur time.Duration) {
+	c.set(k, v, c.clock.Now()+int64(dur), int64(dur))
+}
+
+// set store v with an absolute expiration
+func (c *TemplateCache[K, V]) set(k K, v V, expiration, duration int64) {
 	c.mu.Lock()
 	i, ok := c.items[k]
 	if ok {
 		i.obj = v
-		i.expiration = time.Now().Add(dur).UnixNano()
-		i.duration = int64(dur)
-		// c.items[k] = i
+		i.expiration = expiration
+		i.duration = duration
+		c.items[k] = i
 		c.mu.Unlock()
 		return
 	}
-	c.items[k] = TemplateItem[K, V]{
+	c.insert(k, TemplateItem[K, V]{
 		obj:        v,
-		expiration: time.Now().Add(dur).UnixNano(),
-		duration:   int64(dur),
-		color:      black,
-	}
+		expiration: expiration,
+		duration:   duration,
+	})
 	c.mu.Unlock()
 }
 
+// insert add a new entry, in bounded mode it takes a slot of the clock
+// ring and starts black. The write lock must be held.
+func (c *TemplateCache[K, V]) insert(k K, item TemplateItem[K, V]) {
+	if c.capacity == 0 {
+		item.color = white
+		c.items[k] = item
+		return
+	}
+	item.color = black
+	if c.order.len() < c.capacity {
+		item.slot = c.order.add(k)
+		c.items[k] = item
+		return
+	}
+	now := c.clock.Now()
+	slot := c.order.sweep(func(k K) bool {
+		i := c.items[k]
+		if i.Disuse() || i.expiredAt(now) {
+			return false
+		}
+		i.color = black
+		c.items[k] = i
+		return true
+	})
+	delete(c.items, c.order.keys[slot])
+	c.order.set(slot, k)
+	item.slot = slot
+	c.items[k] = item
+}
+
 func (c *TemplateCache[K, V]) refresh(k K, tItem TemplateItem[K, V]) {
 	if c.caller == nil {
 		return
 	}
-	t := tItem.expiration - time.Now().UnixNano()
+	t := tItem.expiration - c.clock.Now()
 	if t > 0 && t*100/tItem.duration < 30 {
 		if c.randfunc != nil && !c.randfunc(t, tItem.duration) {
 			return
@@ -135,17 +194,10 @@ func (c *TemplateCache[K, V]) refresh(k K, tItem TemplateItem[K, V]) {
 	}
 }
 
-// Load load keys avoid concurrent large traffic penetration
+// Load load keys avoid concurrent large traffic penetration,
+// see Warm for the concurrency, rate limit and failures
 func (c *TemplateCache[K, V]) Load(ks []K) {
-	if c.caller == nil {
-		return
-	}
-	for _, k := range ks {
-		v, err := c.caller(k)
-		if err == nil {
-			c.SetWithExp(k, v, c.defaultDuration)
-		}
-	}
+	c.Warm(context.Background(), ks)
 }
 
 func (c *TemplateCache[K, V]) deleteExpired() {
@@ -1,7 +1,9 @@
 package basic
 
 import (
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -14,10 +16,58 @@ const (
 type PartitionCache[K comparable, V any] struct {
 	noCopy
 	defaultDuration time.Duration
-	mask            uintptr
-	buckets         []bucket[K, V]
+	capacity        int
+	loadBatch       int
+	idx             *KeyIndex[K]
+	stamps          stamps
+	table           atomic.Pointer[partitionTable[K, V]]
+	resizeMu        sync.Mutex
 	randfunc        func(int64, int64) bool
 	caller          func(K) (V, error)
+	bulk            *BulkLoader[K, V]
+	hot             *HotKeyTracker[K]
+	replicas        *HotReplicas[K, TemplateItem[K, V]]
+	codec           Codec[K, V]
+	wal             atomic.Pointer[WAL[K, V]]
+	nsMu            sync.Mutex
+	namespaces      map[string]*Namespace[K, V]
+	janitor         *Janitor
+}
+
+// stamps are the counters shared by every shard of a cache: the epoch
+// InvalidateAll moves on, and the source of entry versions. The clock
+// tells the time of expirations.
+type stamps struct {
+	epoch   atomic.Uint64
+	version atomic.Uint64
+	clock   Clock
+}
+
+// partitionTable is one generation of shards. Resize builds a new table and
+// leaves a forwarding pointer in every shard of the old one once it is moved.
+type partitionTable[K comparable, V any] struct {
+	mask    uintptr
+	buckets []bucket[K, V]
+}
+
+// newPartitionTable new table of n shards sharing capacity, 0 is no limit
+func newPartitionTable[K comparable, V any](n int, capacity int, idx *KeyIndex[K], stamps *stamps, replicas *HotReplicas[K, TemplateItem[K, V]]) *partitionTable[K, V] {
+	t := &partitionTable[K, V]{
+		mask:    uintptr(n - 1),
+		buckets: make([]bucket[K, V], n),
+	}
+	size := 0
+	if capacity > 0 {
+		size = (capacity + n - 1) / n
+	}
+	for i := range t.buckets {
+		t.buckets[i].initBucket(size, idx, stamps, replicas)
+	}
+	return t
+}
+
+func (t *partitionTable[K, V]) bucket(h uintptr) *bucket[K, V] {
+	return &(t.buckets[h&t.mask])
 }
 
 // bucket
@@ -26,30 +76,57 @@ type bucket[K comparable, V any] struct {
 	defaultDuration time.Duration
 	mu              sync.RWMutex
 	items           map[uintptr]TemplateItem[K, V]
+	moved           *partitionTable[K, V]
+	size            int
+	order           clockRing[uintptr]
+	idx             *KeyIndex[K]
+	stamps          *stamps
+	replicas        *HotReplicas[K, TemplateItem[K, V]]
 }
 
 func (b *bucket[K, V]) clean() {
 }
 
-func (b *bucket[K, V]) initBucket() {
+func (b *bucket[K, V]) initBucket(size int, idx *KeyIndex[K], stamps *stamps, replicas *HotReplicas[K, TemplateItem[K, V]]) {
 	b.items = make(map[uintptr]TemplateItem[K, V])
+	b.size = size
+	b.idx = idx
+	b.stamps = stamps
+	b.replicas = replicas
 }
 
-// NewPartitionCache new cache
-func NewPartitionCache[K comparable, V any]() *PartitionCache[K, V] {
+// NewPartitionCache new cache, unbounded unless WithCapacity is given
+func NewPartitionCache[K comparable, V any](opts ...Option) *PartitionCache[K, V] {
+	o := NewOptions(opts...)
 	c := &PartitionCache[K, V]{
-		mask:            uintptr(InitialSize - 1),
-		buckets:         make([]bucket[K, V], InitialSize),
 		defaultDuration: 10 * time.Second,
 		randfunc:        randfunc,
+		capacity:        o.Capacity,
+		loadBatch:       o.LoadBatch,
+		idx:             NewKeyIndex[K](o.PrefixIndex),
+		hot:             NewHotKeyTracker[K](o.HotKeys, o.HotKeySample),
+		replicas:        NewHotReplicas[K, TemplateItem[K, V]](o.HotKeyReplicas),
 	}
-	c.initBucket()
+	c.stamps.clock = o.Clock
+	c.table.Store(newPartitionTable[K, V](o.Shards, o.Capacity, c.idx, &c.stamps, c.replicas))
+	j := NewClockJanitor(o.Clock, 1*time.Second, c.deleteExpired)
+	runtime.SetFinalizer(c, (*PartitionCache[K, V]).clean)
+	c.janitor = j
 	return c
 }
 
-func (c *PartitionCache[K, V]) initBucket() {
-	for i := range c.buckets {
-		c.buckets[i].initBucket()
+func (c *PartitionCache[K, V]) clean() {
+	c.Close()
+}
+
+// Close stop the janitor of the cache. The janitor keeps the cache
+// reachable, so it is not stopped by the garbage collector: a cache that
+// is dropped must be closed. The cache stays usable, expired entries are
+// then only dropped when they are read, evicted or set again.
+func (c *PartitionCache[K, V]) Close() {
+	if c.janitor != nil {
+		c.janitor.Stop()
+		c.janitor = nil
 	}
 }
 
@@ -62,25 +139,58 @@ func (c *PartitionCache[K, V]) WithCallback(call func(K) (V, error)) {
 func (c *PartitionCache[K, V]) WithRandfunc(call func(int64, int64) bool) {
 	c.randfunc = call
 }
+
+// Shards return the current number of shards
+func (c *PartitionCache[K, V]) Shards() int {
+	return len(c.table.Load().buckets)
+}
+
+// Capacity return the number of entries the cache holds at most, 0 is
+// no limit
+func (c *PartitionCache[K, V]) Capacity() int {
+	return c.capacity
+}
+
+// Resize change the number of shards to n, rounded up to a power of two.
+// Entries are migrated one shard at a time: only the shard being moved is
+// locked, so readers and writers of every other shard carry on as usual.
+func (c *PartitionCache[K, V]) Resize(n int) {
+	c.resizeMu.Lock()
+	defer c.resizeMu.Unlock()
+	old := c.table.Load()
+	n = ShardCount(n)
+	if n == len(old.buckets) {
+		return
+	}
+	next := newPartitionTable[K, V](n, c.capacity, c.idx, &c.stamps, c.replicas)
+	for i := range old.buckets {
+		old.buckets[i].migrate(next)
+	}
+	c.table.Store(next)
+}
+
 func ehash(i interface{}) uintptr {
-	return nilinterhash(noescape(unsafe.Pointer(&i)), 0xdeadbeef)
+	return nilinterhash(unsafe.Pointer(&i), 0xdeadbeef)
 }
 
+//go:noescape
 //go:linkname nilinterhash runtime.nilinterhash
 func nilinterhash(p unsafe.Pointer, h uintptr) uintptr
 
-//go:nocheckptr
-//go:nosplit
-func noescape(p unsafe.Pointer) unsafe.Pointer {
-	x := uintptr(p)
-	return unsafe.Pointer(x ^ 0)
-}
-
+// Get value of k, loading it with the callback on a miss
+// error maybe not found, timeout
 func (c *PartitionCache[K, V]) Get(k K) (r V, err error) {
 	hash := ehash(k)
-	i := hash & c.mask
-	b := &(c.buckets[i])
-	return b.Get(c, k, hash)
+	t := c.table.Load()
+	if !c.hot.Observe(k, int(hash&t.mask), len(t.buckets)) {
+		// unsampled Gets of a replicated key skip the shard lock
+		if item, ok := c.replicas.Load(k); ok && item.epoch == c.stamps.epoch.Load() && !item.expiredAt(c.stamps.clock.Now()) {
+			item.hits.Add(1)
+			t.bucket(hash).refresh(c, nil, k, hash, *item)
+			return item.obj, nil
+		}
+	}
+	return t.bucket(hash).Get(c, nil, k, hash)
 }
 
 func (c *PartitionCache[K, V]) Set(k K, v V) {
@@ -90,84 +200,320 @@ func (c *PartitionCache[K, V]) Set(k K, v V) {
 // SetWithExp actively set bucket value
 func (c *PartitionCache[K, V]) SetWithExp(k K, v V, dur time.Duration) {
 	hash := ehash(k)
-	i := hash & c.mask
-	b := &(c.buckets[i])
-	b.SetWithExp(k, v, hash, dur)
+	b := c.table.Load().bucket(hash)
+	b.SetWithExp(k, v, hash, dur, c.wal.Load())
+}
+
+// Delete remove k
+func (c *PartitionCache[K, V]) Delete(k K) {
+	hash := ehash(k)
+	c.table.Load().bucket(hash).delete(k, hash, nil, OpDelete, nil, c.wal.Load())
+}
+
+// InvalidateAll drop every entry in O(1), namespaces included: they
+// become misses at once and the janitor reclaims them later, so readers
+// are never stalled behind a walk of the shards. It is the Clear of the
+// cache, logged to the WAL, if any.
+func (c *PartitionCache[K, V]) InvalidateAll() {
+	c.stamps.epoch.Add(1)
+	c.nsMu.Lock()
+	for _, ns := range c.namespaces {
+		ns.Bump()
+	}
+	c.nsMu.Unlock()
+	w := c.wal.Load()
+	w.Wait(w.Append(OpClear, &Entry[K, V]{}))
 }
 
+// deleteExpired is the janitor pass: it reclaims expired and invalidated
+// entries and picks the hot keys to replicate
 func (c *PartitionCache[K, V]) deleteExpired() {
-	// TODO:
+	t := c.table.Load()
+	for i := range t.buckets {
+		t.buckets[i].deleteExpired(c.wal.Load())
+	}
+	c.replicas.Follow(c.hot)
+}
+
+// rlock read-lock the bucket owning h, following the forwarding pointer
+// left behind by Resize
+func (b *bucket[K, V]) rlock(h uintptr) *bucket[K, V] {
+	for {
+		b.mu.RLock()
+		if b.moved == nil {
+			return b
+		}
+		next := b.moved.bucket(h)
+		b.mu.RUnlock()
+		b = next
+	}
 }
 
-// Get bucket value
+// lock write-lock the bucket owning h, following the forwarding pointer
+// left behind by Resize
+func (b *bucket[K, V]) lock(h uintptr) *bucket[K, V] {
+	for {
+		b.mu.Lock()
+		if b.moved == nil {
+			return b
+		}
+		next := b.moved.bucket(h)
+		b.mu.Unlock()
+		b = next
+	}
+}
+
+// migrate move every entry into next and leave a forwarding pointer behind.
+// Lock order is always old shard before new shard.
+func (b *bucket[K, V]) migrate(next *partitionTable[K, V]) {
+	b.mu.Lock()
+	for h, item := range b.items {
+		t := next.bucket(h)
+		t.mu.Lock()
+		t.insert(h, item)
+		t.mu.Unlock()
+	}
+	b.items = nil
+	b.order = clockRing[uintptr]{}
+	b.moved = next
+	b.mu.Unlock()
+}
+
+// Get bucket value of k in namespace ns, nil is the cache itself
 // error maybe not found, timeout
-func (b *bucket[K, V]) Get(p *PartitionCache[K, V], k K, h uintptr) (r V, err error) {
-	b.mu.RLock()
+func (b *bucket[K, V]) Get(p *PartitionCache[K, V], ns *Namespace[K, V], k K, h uintptr) (r V, err error) {
+	b = b.rlock(h)
 	item, ok := b.items[h]
-	if !ok || item.k != k {
-		if p.caller == nil {
+	ok = ok && item.owned(k, ns, b.stamps.epoch.Load())
+	if ok && ns == nil {
+		b.replicas.Fill(k, item)
+	}
+	b.mu.RUnlock()
+	if !ok {
+		ns.miss()
+		caller := p.callerOf(ns)
+		if caller == nil {
 			return r, NotFound
 		}
-		b.mu.RUnlock()
-		v, err := p.caller(k)
+		v, err := caller(k)
+		ns.loaded(err)
 		if err != nil {
 			return r, NotFound
 		}
-		b.SetWithExp(k, v, h, p.defaultDuration)
+		b.put(h, p.item(ns, k, v, p.durationOf(ns), nil), p.wal.Load())
 		return v, nil
 	}
-	b.mu.RUnlock()
-	if item.Expired() {
-		b.refresh(p, k, h, item)
-		return item.obj, Timeout
-	}
+	ns.hit()
+	item.hits.Add(1)
 	if item.Disuse() {
-		b.refresh(p, k, h, item)
-		return item.obj, Disuse
+		b.use(k, h)
 	}
-	b.refresh(p, k, h, item)
+	if item.expiredAt(b.now()) {
+		b.refresh(p, ns, k, h, item)
+		return item.obj, Timeout
+	}
+	b.refresh(p, ns, k, h, item)
 	return item.obj, nil
 }
 
+// use color k white, so the clock hand passes it once more
+func (b *bucket[K, V]) use(k K, h uintptr) {
+	b = b.lock(h)
+	if i, ok := b.items[h]; ok && i.k == k {
+		i.color = white
+		b.items[h] = i
+	}
+	b.mu.Unlock()
+}
+
 // SetWithExp actively set bucket value
-func (b *bucket[K, V]) SetWithExp(k K, v V, h uintptr, dur time.Duration) {
-	b.mu.Lock()
+func (b *bucket[K, V]) SetWithExp(k K, v V, h uintptr, dur time.Duration, w *WAL[K, V]) {
+	b.set(k, v, h, b.now()+int64(dur), int64(dur), nil, w)
+}
+
+// set store v with an absolute expiration and tags, and log it to w, if any
+func (b *bucket[K, V]) set(k K, v V, h uintptr, expiration, duration int64, tags []string, w *WAL[K, V]) {
+	b.put(h, TemplateItem[K, V]{
+		k:          k,
+		obj:        v,
+		expiration: expiration,
+		duration:   duration,
+		tags:       tags,
+	}, w)
+}
+
+// put store item, replacing the entry of the same key, namespace and
+// generation, or whatever else sits on h. Entries of the cache itself are
+// logged to w, if any; a namespace over its quota drops new keys.
+func (b *bucket[K, V]) put(h uintptr, item TemplateItem[K, V], w *WAL[K, V]) {
+	b = b.lock(h)
+	_, seq := b.store(h, item, w)
+	b.mu.Unlock()
+	w.Wait(seq)
+}
+
+// store is put under the write lock, it reports whether item was stored
+// and returns the sequence number of its record for Wait
+func (b *bucket[K, V]) store(h uintptr, item TemplateItem[K, V], w *WAL[K, V]) (bool, uint64) {
+	item.epoch = b.stamps.epoch.Load()
+	item.version = b.stamps.version.Add(1)
 	i, ok := b.items[h]
-	if ok {
-		i.k = k
-		i.obj = v
-		i.expiration = time.Now().Add(dur).UnixNano()
-		i.duration = int64(dur)
-		// b.items[k] = i
+	if ok && i.k == item.k && i.ns == item.ns && i.gen == item.gen && i.epoch == item.epoch {
+		if i.ns == nil {
+			b.idx.Update(h, i.k, i.tags, item.tags)
+		}
+		i.obj = item.obj
+		i.expiration = item.expiration
+		i.duration = item.duration
+		i.tags = item.tags
+		i.version = item.version
+		b.items[h] = i
+		item = i
+	} else {
+		if ok {
+			b.remove(h, i)
+		}
+		if !item.ns.admit() {
+			return false, 0
+		}
+		item.created = b.now()
+		item.hits = new(atomic.Uint64)
+		b.insert(h, item)
+		if item.ns == nil {
+			b.idx.Insert(h, item.k, item.tags)
+		}
+	}
+	if item.ns != nil {
+		return true, 0
+	}
+	b.replicas.Store(item.k, item)
+	return true, w.Append(OpSet, &Entry[K, V]{Key: item.k, Value: item.obj, Expiration: item.expiration, Duration: item.duration, Tags: item.tags})
+}
+
+// delete remove k of namespace ns when cond, if any, holds for it and
+// log it to w as op, if any. It reports whether k was removed.
+func (b *bucket[K, V]) delete(k K, h uintptr, ns *Namespace[K, V], op Op, cond func(TemplateItem[K, V]) bool, w *WAL[K, V]) bool {
+	b = b.lock(h)
+	i, ok := b.items[h]
+	if !ok || !i.owned(k, ns, b.stamps.epoch.Load()) || (cond != nil && !cond(i)) {
 		b.mu.Unlock()
+		return false
+	}
+	b.remove(h, i)
+	seq := w.Append(op, &Entry[K, V]{Key: k})
+	b.mu.Unlock()
+	w.Wait(seq)
+	return true
+}
+
+// remove drop the entry of h and free its clock slot, the write lock
+// must be held
+func (b *bucket[K, V]) remove(h uintptr, item TemplateItem[K, V]) {
+	delete(b.items, h)
+	b.forget(h, item)
+	if b.size == 0 {
 		return
 	}
-	b.items[h] = TemplateItem[K, V]{
-		k:          k,
-		obj:        v,
-		expiration: time.Now().Add(dur).UnixNano(),
-		duration:   int64(dur),
-		color:      black,
+	if moved, ok := b.order.remove(item.slot); ok {
+		i := b.items[moved]
+		i.slot = item.slot
+		b.items[moved] = i
 	}
-	b.mu.Unlock()
 }
 
-func (b *bucket[K, V]) refresh(p *PartitionCache[K, V], k K, h uintptr, tItem TemplateItem[K, V]) {
-	if p.caller == nil {
+// insert add a new entry, in bounded mode it takes a slot of the clock
+// ring and starts black. The write lock must be held.
+func (b *bucket[K, V]) insert(h uintptr, item TemplateItem[K, V]) {
+	if b.size == 0 {
+		item.color = white
+		b.items[h] = item
+		return
+	}
+	item.color = black
+	if b.order.len() < b.size {
+		item.slot = b.order.add(h)
+		b.items[h] = item
+		return
+	}
+	epoch, now := b.stamps.epoch.Load(), b.now()
+	slot := b.order.sweep(func(h uintptr) bool {
+		i := b.items[h]
+		if i.Disuse() || i.expiredAt(now) || i.stale(epoch) {
+			return false
+		}
+		i.color = black
+		b.items[h] = i
+		return true
+	})
+	victim := b.order.keys[slot]
+	b.forget(victim, b.items[victim])
+	delete(b.items, victim)
+	b.order.set(slot, h)
+	item.slot = slot
+	b.items[h] = item
+}
+
+// now is the time of the clock of the cache
+func (b *bucket[K, V]) now() int64 {
+	return b.stamps.clock.Now()
+}
+
+// forget drop item from the key index and the hot key replicas, or from
+// the count of its namespace, the write lock must be held
+func (b *bucket[K, V]) forget(h uintptr, item TemplateItem[K, V]) {
+	if item.ns != nil {
+		item.ns.release(item.gen)
 		return
 	}
-	t := tItem.expiration - time.Now().UnixNano()
+	b.idx.Remove(h, item.k, item.tags)
+	b.replicas.Drop(item.k)
+}
+
+func (b *bucket[K, V]) refresh(p *PartitionCache[K, V], ns *Namespace[K, V], k K, h uintptr, tItem TemplateItem[K, V]) {
+	caller := p.callerOf(ns)
+	if caller == nil {
+		return
+	}
+	t := tItem.expiration - b.now()
 	if t > 0 && t*100/tItem.duration < 30 {
 		if p.randfunc != nil && !p.randfunc(t, tItem.duration) {
 			return
 		}
-		v, err := p.caller(k)
+		v, err := caller(k)
+		ns.loaded(err)
 		if err == nil {
-			b.SetWithExp(k, v, h, p.defaultDuration)
+			b.put(h, p.item(ns, k, v, p.durationOf(ns), nil), p.wal.Load())
 		}
 	}
 }
 
-func (b *bucket[K, V]) deleteExpired() {
-	// TODO:
+// deleteExpired look at up to ScanNums entries, removing up to
+// DeleteNums expired ones, logging them to w, if any, and up to
+// StaleDeleteNums entries invalidated by InvalidateAll or Bump
+func (b *bucket[K, V]) deleteExpired(w *WAL[K, V]) {
+	now := b.now()
+	i, j, n := 0, 0, 0
+	var seq uint64
+	b.mu.Lock()
+	epoch := b.stamps.epoch.Load()
+	for h, item := range b.items {
+		if n >= ScanNums || (i >= DeleteNums && j >= StaleDeleteNums) {
+			break
+		}
+		n++
+		if item.stale(epoch) {
+			if j < StaleDeleteNums {
+				j++
+				b.remove(h, item)
+			}
+		} else if i < DeleteNums && item.expiration != 0 && item.expiration < now {
+			i++
+			b.remove(h, item)
+			if item.ns == nil {
+				seq = w.Append(OpExpire, &Entry[K, V]{Key: item.k})
+			}
+		}
+	}
+	b.mu.Unlock()
+	w.Wait(seq)
 }
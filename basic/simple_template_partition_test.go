@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 
 func TestPartitionCache(t *testing.T) {
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	cache.WithCallback(getmessage2)
 
 	Convey(fmt.Sprintf("key %v expect %v", "123", "value_123"), t, func() {
@@ -24,8 +26,197 @@ func TestPartitionCache(t *testing.T) {
 	})
 }
 
+func TestPartitionCacheResize(t *testing.T) {
+	Convey("shard count follows the option and survives resize", t, func() {
+		cache := NewPartitionCache[string, []byte](WithShards(5))
+		defer cache.Close()
+		So(cache.Shards(), ShouldEqual, 8)
+		for i := 0; i < 1000; i++ {
+			cache.Set(strconv.Itoa(i), []byte(strconv.Itoa(i)))
+		}
+		cache.Resize(64)
+		So(cache.Shards(), ShouldEqual, 64)
+		for i := 0; i < 1000; i++ {
+			value, err := cache.Get(strconv.Itoa(i))
+			So(err, ShouldBeNil)
+			So(string(value), ShouldEqual, strconv.Itoa(i))
+		}
+	})
+
+	Convey("concurrent readers and writers during resize", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(2))
+		defer cache.Close()
+		for i := 0; i < 1000; i++ {
+			cache.Set(i, i)
+		}
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					cache.Set(1000+w, i)
+					cache.Get(i % 1000)
+				}
+			}(w)
+		}
+		cache.Resize(128)
+		cache.Resize(4)
+		close(stop)
+		wg.Wait()
+		So(cache.Shards(), ShouldEqual, 4)
+		for i := 0; i < 1000; i++ {
+			value, err := cache.Get(i)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, i)
+		}
+	})
+}
+
+func TestPartitionCacheClock(t *testing.T) {
+	Convey("entries are not reported as disused", t, func() {
+		cache := NewPartitionCache[string, []byte]()
+		defer cache.Close()
+		cache.Set("a", message2)
+		_, err := cache.Get("a")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("bounded mode evicts entries the hand finds unused", t, func() {
+		cache := NewPartitionCache[string, []byte](WithCapacity(3), WithShards(1))
+		defer cache.Close()
+		for _, k := range []string{"a", "b", "c"} {
+			cache.Set(k, message2)
+		}
+		cache.Get("a")
+		cache.Get("c")
+		cache.Set("d", message2)
+		_, err := cache.Get("b")
+		So(err, ShouldEqual, NotFound)
+		for _, k := range []string{"a", "c", "d"} {
+			_, err := cache.Get(k)
+			So(err, ShouldBeNil)
+		}
+		for i := 0; i < 100; i++ {
+			cache.Set(strconv.Itoa(i), message2)
+		}
+		So(len(cache.table.Load().buckets[0].items), ShouldBeLessThanOrEqualTo, 4)
+	})
+
+	Convey("delete frees the clock slot in bounded mode", t, func() {
+		cache := NewPartitionCache[string, []byte](WithCapacity(3), WithShards(1))
+		defer cache.Close()
+		for _, k := range []string{"a", "b", "c"} {
+			cache.Set(k, message2)
+		}
+		cache.Delete("a")
+		cache.Delete("missing")
+		_, err := cache.Get("a")
+		So(err, ShouldEqual, NotFound)
+		cache.Set("d", message2)
+		b := &cache.table.Load().buckets[0]
+		So(b.order.len(), ShouldEqual, 3)
+		for slot, h := range b.order.keys {
+			So(b.items[h].slot, ShouldEqual, slot)
+		}
+	})
+}
+
+func TestPartitionCacheInvalidateAll(t *testing.T) {
+	Convey("invalidate all hides every entry at once", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(4))
+		defer cache.Close()
+		ns := cache.Namespace("n")
+		for i := 0; i < 100; i++ {
+			cache.SetWithTags(i, i, time.Minute, "t")
+			ns.Set(i, i)
+		}
+		cache.InvalidateAll()
+		_, err := cache.Get(1)
+		So(err, ShouldEqual, NotFound)
+		_, err = ns.Get(1)
+		So(err, ShouldEqual, NotFound)
+		So(ns.Len(), ShouldEqual, 0)
+		So(cache.InvalidateTag("t"), ShouldEqual, 0)
+		cache.Set(1, 10)
+		v, err := cache.Get(1)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 10)
+	})
+
+	Convey("the janitor reclaims invalidated and expired entries", t, func() {
+		cache := NewPartitionCache[int, int](WithShards(2))
+		defer cache.Close()
+		for i := 0; i < 50; i++ {
+			cache.Set(i, i)
+			cache.SetWithExp(i+100, i, time.Nanosecond)
+		}
+		cache.InvalidateAll()
+		cache.Set(1, 1)
+		for i := 0; i < 20; i++ {
+			cache.deleteExpired()
+		}
+		n := 0
+		t := cache.table.Load()
+		for i := range t.buckets {
+			n += len(t.buckets[i].items)
+		}
+		So(n, ShouldEqual, 1)
+	})
+
+	Convey("the janitor reclaims a large invalidated cache in a few ticks", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewPartitionCache[int, int](WithClock(clock), WithShards(4))
+		defer cache.Close()
+		for i := 0; i < 20000; i++ {
+			cache.Set(i, i)
+		}
+		cache.InvalidateAll()
+		for i := 0; i < 8; i++ {
+			clock.Advance(time.Second)
+		}
+		n := 0
+		t := cache.table.Load()
+		for i := range t.buckets {
+			n += len(t.buckets[i].items)
+		}
+		So(n, ShouldEqual, 0)
+		So(cache.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestPartitionCacheJanitorScan(t *testing.T) {
+	Convey("a janitor pass only looks at part of a large shard", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewPartitionCache[int, int](WithClock(clock), WithShards(1))
+		defer cache.Close()
+		for i := 0; i < 100; i++ {
+			cache.Set(-i-1, i)
+		}
+		cache.InvalidateAll()
+		for i := 0; i < 8*ScanNums; i++ {
+			cache.SetWithExp(i, i, time.Hour)
+		}
+		b := &cache.table.Load().buckets[0]
+		clock.Advance(time.Second)
+		So(len(b.items), ShouldBeGreaterThan, 8*ScanNums)
+		// every pass starts somewhere else, the stale entries go in time
+		for i := 0; i < 200 && len(b.items) > 8*ScanNums; i++ {
+			clock.Advance(time.Second)
+		}
+		So(len(b.items), ShouldEqual, 8*ScanNums)
+	})
+}
+
 func BenchmarkGetPartitionCache(b *testing.B) {
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	cache.WithCallback(getmessage2)
 	for i := 0; i < b.N; i++ {
 		cache.Get("123")
@@ -57,6 +248,7 @@ func BenchmarkWriteToPartitionCache(b *testing.B) {
 
 func writeToPartitionCache(b *testing.B, data []byte) {
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	rand.Seed(time.Now().Unix())
 
 	b.RunParallel(func(pb *testing.PB) {
@@ -77,6 +269,7 @@ func BenchmarkReadFromPartitionCache(b *testing.B) {
 
 func readFromPartitionCache(b *testing.B) {
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	cache.WithCallback(getmessage2)
 	for i := 0; i < b.N; i++ {
 		cache.SetWithExp(strconv.Itoa(i), message2, 100*time.Second)
@@ -98,6 +291,7 @@ func BenchmarkReadFromPartitionCacheNonExistentKeys(b *testing.B) {
 
 func readFromPartitionCacheNonExistentKeys(b *testing.B) {
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	cache.WithCallback(getmessage2)
 	b.ResetTimer()
 
@@ -127,6 +321,7 @@ func readFromPartitionCacheKKeys(b *testing.B, l int) {
 		keys[i] = i
 	}
 	cache := NewPartitionCache[string, []byte]()
+	defer cache.Close()
 	cache.WithCallback(getmessage2)
 	b.ResetTimer()
 
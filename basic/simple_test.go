@@ -35,6 +35,35 @@ func getmessage(key string) (m interface{}, err error) {
 	return message, nil
 }
 
+func TestSimpleCacheClock(t *testing.T) {
+	Convey("entries are not reported as disused", t, func() {
+		cache := NewSimpleCache()
+		cache.Set("a", message2)
+		_, err := cache.Get("a")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("bounded mode evicts entries the hand finds unused", t, func() {
+		cache := NewSimpleCache(WithCapacity(3))
+		for _, k := range []string{"a", "b", "c"} {
+			cache.Set(k, message2)
+		}
+		cache.Get("a")
+		cache.Get("c")
+		cache.Set("d", message2)
+		_, err := cache.Get("b")
+		So(err, ShouldEqual, NotFound)
+		for _, k := range []string{"a", "c", "d"} {
+			_, err := cache.Get(k)
+			So(err, ShouldBeNil)
+		}
+		for i := 0; i < 100; i++ {
+			cache.Set(strconv.Itoa(i), message2)
+		}
+		So(len(cache.items), ShouldBeLessThanOrEqualTo, 4)
+	})
+}
+
 func BenchmarkGetCache(b *testing.B) {
 	cache := NewSimpleCache()
 	cache.WithCallback(getmessage)
@@ -0,0 +1,178 @@
+package basic
+
+// seeds used to derive the rows of the sketch from a single hash
+var seeds = [4]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// CountMinSketch estimate how often a hash was seen with 4 rows of 4-bit
+// counters. Every sampleSize increments all counters are halved, so old
+// popularity fades away.
+// It is not safe for concurrent use.
+type CountMinSketch struct {
+	table      []uint64
+	mask       uint64
+	size       int
+	sampleSize int
+}
+
+// NewCountMinSketch new sketch sized for capacity distinct entries
+func NewCountMinSketch(capacity int) *CountMinSketch {
+	if capacity < 1 {
+		capacity = 1
+	}
+	n := 1
+	for n < capacity {
+		n <<= 1
+	}
+	return &CountMinSketch{
+		table:      make([]uint64, n),
+		mask:       uint64(n - 1),
+		sampleSize: 10 * capacity,
+	}
+}
+
+func (s *CountMinSketch) index(h uint64, i int) (int, uint64) {
+	h = (h + seeds[i]) * seeds[i]
+	h += h >> 32
+	return int(h & s.mask), ((h >> 32) & 15) << 2
+}
+
+// Increment add one to every row of h, counters saturate at 15.
+// It reports whether the sketch was aged by this call.
+func (s *CountMinSketch) Increment(h uint64) bool {
+	added := false
+	for i := range seeds {
+		w, off := s.index(h, i)
+		if (s.table[w]>>off)&0xf < 15 {
+			s.table[w] += 1 << off
+			added = true
+		}
+	}
+	if added {
+		s.size++
+		if s.size >= s.sampleSize {
+			s.reset()
+			return true
+		}
+	}
+	return false
+}
+
+// Estimate return the smallest counter of h
+func (s *CountMinSketch) Estimate(h uint64) int {
+	min := 15
+	for i := range seeds {
+		w, off := s.index(h, i)
+		if c := int((s.table[w] >> off) & 0xf); c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// reset halve every counter
+func (s *CountMinSketch) reset() {
+	for i := range s.table {
+		s.table[i] = (s.table[i] >> 1) & 0x7777777777777777
+	}
+	s.size /= 2
+}
+
+// doorkeeper is a small bloom filter in front of the sketch: a hash has to
+// be seen twice before it takes room in the counters.
+type doorkeeper struct {
+	bits []uint64
+	mask uint64
+}
+
+// newDoorkeeper new filter with about 32 bits for each of the n hashes
+// it sees between two resets. A false positive lets a key seen once
+// count twice, enough to beat a victim with one recent access, so the
+// filter is kept sparse: under 1% of false positives with two hashes.
+func newDoorkeeper(n int) *doorkeeper {
+	w := 1
+	for w*64 < n*32 {
+		w <<= 1
+	}
+	return &doorkeeper{
+		bits: make([]uint64, w),
+		mask: uint64(w*64 - 1),
+	}
+}
+
+// add set the bits of h and report whether they were all set already
+func (d *doorkeeper) add(h uint64) bool {
+	seen := true
+	for i := 0; i < 2; i++ {
+		b := ((h + seeds[i]) * seeds[i] >> 16) & d.mask
+		if d.bits[b>>6]&(1<<(b&63)) == 0 {
+			seen = false
+			d.bits[b>>6] |= 1 << (b & 63)
+		}
+	}
+	return seen
+}
+
+func (d *doorkeeper) contains(h uint64) bool {
+	for i := 0; i < 2; i++ {
+		b := ((h + seeds[i]) * seeds[i] >> 16) & d.mask
+		if d.bits[b>>6]&(1<<(b&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *doorkeeper) reset() {
+	for i := range d.bits {
+		d.bits[i] = 0
+	}
+}
+
+// TinyLFU is a frequency based admission filter: a new entry only replaces
+// an eviction victim when it has been seen more often recently.
+// It is not safe for concurrent use, callers guard it with their own lock.
+type TinyLFU struct {
+	sketch     *CountMinSketch
+	door       *doorkeeper
+	additions  int
+	sampleSize int
+}
+
+// NewTinyLFU new filter for a cache holding capacity entries
+func NewTinyLFU(capacity int) *TinyLFU {
+	s := NewCountMinSketch(capacity)
+	return &TinyLFU{
+		sketch:     s,
+		door:       newDoorkeeper(s.sampleSize),
+		sampleSize: s.sampleSize,
+	}
+}
+
+// Increment record one access to h.
+// Every sampleSize accesses the sketch is aged and the doorkeeper cleared.
+func (t *TinyLFU) Increment(h uint64) {
+	t.additions++
+	if t.additions >= t.sampleSize {
+		t.additions = 0
+		t.sketch.reset()
+		t.door.reset()
+	}
+	if !t.door.add(h) {
+		return
+	}
+	t.sketch.Increment(h)
+}
+
+// Estimate return the recent access frequency of h
+func (t *TinyLFU) Estimate(h uint64) int {
+	n := t.sketch.Estimate(h)
+	if t.door.contains(h) {
+		n++
+	}
+	return n
+}
+
+// Admit report whether candidate should replace victim
+func (t *TinyLFU) Admit(candidate, victim uint64) bool {
+	return t.Estimate(candidate) > t.Estimate(victim)
+}
@@ -0,0 +1,48 @@
+package basic
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	Convey("estimates grow with increments and age by half", t, func() {
+		s := NewCountMinSketch(64)
+		for i := 0; i < 10; i++ {
+			s.Increment(42)
+		}
+		So(s.Estimate(42), ShouldEqual, 10)
+		So(s.Estimate(43), ShouldBeLessThan, 10)
+		for i := 0; i < 10; i++ {
+			s.Increment(42)
+		}
+		So(s.Estimate(42), ShouldEqual, 15)
+		s.reset()
+		So(s.Estimate(42), ShouldEqual, 7)
+	})
+}
+
+func TestTinyLFU(t *testing.T) {
+	Convey("frequent keys are admitted over rare ones", t, func() {
+		f := NewTinyLFU(100)
+		for i := 0; i < 5; i++ {
+			f.Increment(1)
+		}
+		f.Increment(2)
+		So(f.Estimate(2), ShouldEqual, 1)
+		So(f.Admit(1, 2), ShouldBeTrue)
+		So(f.Admit(2, 1), ShouldBeFalse)
+		So(f.Admit(3, 2), ShouldBeFalse)
+	})
+
+	Convey("aging clears the doorkeeper", t, func() {
+		f := NewTinyLFU(1)
+		f.Increment(7)
+		So(f.door.contains(7), ShouldBeTrue)
+		for i := 0; i < 20; i++ {
+			f.Increment(7)
+		}
+		So(f.Estimate(7), ShouldBeLessThan, 15)
+	})
+}
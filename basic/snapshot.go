@@ -0,0 +1,209 @@
+package basic
+
+import "io"
+
+// codecOr return c, or gob when no codec was set
+func codecOr[K comparable, V any](c Codec[K, V]) Codec[K, V] {
+	if c == nil {
+		return GobCodec[K, V]{}
+	}
+	return c
+}
+
+// EncodeEntries write entries with enc
+func EncodeEntries[K comparable, V any](enc Encoder[K, V], entries []Entry[K, V]) error {
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeEntries pass every entry of dec that is not expired yet by clock
+// to set
+func DecodeEntries[K comparable, V any](clock Clock, dec Decoder[K, V], set func(*Entry[K, V])) error {
+	now := clock.Now()
+	for {
+		var e Entry[K, V]
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if e.Expired(now) {
+			continue
+		}
+		set(&e)
+	}
+}
+
+// WithCodec set the codec of Snapshot and Restore, gob by default
+func (c *PartitionCache[K, V]) WithCodec(codec Codec[K, V]) {
+	c.codec = codec
+}
+
+// Snapshot write every live entry to w. Shards are copied one at a time
+// under their read lock and encoded after it is released, so writers are
+// only held up for the copy of one shard. Resize waits for it to finish.
+func (c *PartitionCache[K, V]) Snapshot(w io.Writer) error {
+	c.resizeMu.Lock()
+	defer c.resizeMu.Unlock()
+	enc := codecOr(c.codec).NewEncoder(w)
+	t := c.table.Load()
+	var entries []Entry[K, V]
+	for i := range t.buckets {
+		entries = t.buckets[i].entries(entries[:0])
+		if err := EncodeEntries(enc, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore load the entries of a snapshot, keeping their expiration.
+// Entries that expired in the meantime are skipped.
+func (c *PartitionCache[K, V]) Restore(r io.Reader) error {
+	return DecodeEntries(c.stamps.clock, codecOr(c.codec).NewDecoder(r), func(e *Entry[K, V]) {
+		h := ehash(e.Key)
+		c.table.Load().bucket(h).set(e.Key, e.Value, h, e.Expiration, e.Duration, e.Tags, nil)
+	})
+}
+
+// entries append the live entries of the bucket to dst
+func (b *bucket[K, V]) entries(dst []Entry[K, V]) []Entry[K, V] {
+	now := b.now()
+	b.mu.RLock()
+	epoch := b.stamps.epoch.Load()
+	for _, item := range b.items {
+		e := Entry[K, V]{Key: item.k, Value: item.obj, Expiration: item.expiration, Duration: item.duration, Tags: item.tags}
+		if item.ns == nil && !item.stale(epoch) && !e.Expired(now) {
+			dst = append(dst, e)
+		}
+	}
+	b.mu.RUnlock()
+	return dst
+}
+
+// WithCodec set the codec of Snapshot and Restore, gob by default
+func (c *TemplateCache[K, V]) WithCodec(codec Codec[K, V]) {
+	c.codec = codec
+}
+
+// Snapshot write every live entry to w, the entries are copied under the
+// read lock and encoded after it is released
+func (c *TemplateCache[K, V]) Snapshot(w io.Writer) error {
+	now := c.clock.Now()
+	var entries []Entry[K, V]
+	c.mu.RLock()
+	for k, item := range c.items {
+		e := Entry[K, V]{Key: k, Value: item.obj, Expiration: item.expiration, Duration: item.duration}
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	c.mu.RUnlock()
+	return EncodeEntries(codecOr(c.codec).NewEncoder(w), entries)
+}
+
+// Restore load the entries of a snapshot, keeping their expiration.
+// Entries that expired in the meantime are skipped.
+func (c *TemplateCache[K, V]) Restore(r io.Reader) error {
+	return DecodeEntries(c.clock, codecOr(c.codec).NewDecoder(r), func(e *Entry[K, V]) {
+		c.set(e.Key, e.Value, e.Expiration, e.Duration)
+	})
+}
+
+// WithCodec set the codec of Snapshot and Restore, gob by default
+func (c *SieveCache[K, V]) WithCodec(codec Codec[K, V]) {
+	c.codec = codec
+}
+
+// Snapshot write every live entry to w from the oldest to the newest, so
+// Restore rebuilds the same queue. Visited bits are not kept.
+func (c *SieveCache[K, V]) Snapshot(w io.Writer) error {
+	now := c.clock.Now()
+	var entries []Entry[K, V]
+	c.mu.RLock()
+	for n := c.tail; n != nil; n = n.prev {
+		e := Entry[K, V]{Key: n.k, Value: n.obj, Expiration: n.expiration, Duration: n.duration}
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	c.mu.RUnlock()
+	return EncodeEntries(codecOr(c.codec).NewEncoder(w), entries)
+}
+
+// Restore load the entries of a snapshot, keeping their expiration.
+// Entries that expired in the meantime are skipped.
+func (c *SieveCache[K, V]) Restore(r io.Reader) error {
+	return DecodeEntries(c.clock, codecOr(c.codec).NewDecoder(r), func(e *Entry[K, V]) {
+		c.set(e.Key, e.Value, e.Expiration, e.Duration)
+	})
+}
+
+// WithCodec set the codec of Snapshot and Restore, gob by default. The
+// values are interfaces: gob needs their types registered with
+// gob.Register, JSON restores them as the types of encoding/json.
+func (c *SimpleCache) WithCodec(codec Codec[string, any]) {
+	c.codec = codec
+}
+
+// Snapshot write every live entry to w, the entries are copied under the
+// read lock and encoded after it is released
+func (c *SimpleCache) Snapshot(w io.Writer) error {
+	now := c.clock.Now()
+	var entries []Entry[string, any]
+	c.mu.RLock()
+	for k, item := range c.items {
+		e := Entry[string, any]{Key: k, Value: item.obj, Expiration: item.expiration, Duration: item.duration}
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	c.mu.RUnlock()
+	return EncodeEntries(codecOr(c.codec).NewEncoder(w), entries)
+}
+
+// Restore load the entries of a snapshot, keeping their expiration.
+// Entries that expired in the meantime are skipped.
+func (c *SimpleCache) Restore(r io.Reader) error {
+	return DecodeEntries(c.clock, codecOr(c.codec).NewDecoder(r), func(e *Entry[string, any]) {
+		c.set(e.Key, e.Value, e.Expiration, e.Duration)
+	})
+}
+
+// WithCodec set the codec of Snapshot and Restore, see the WithCodec of
+// SimpleCache
+func (c *LRUCache) WithCodec(codec Codec[string, any]) {
+	c.codec = codec
+}
+
+// Snapshot write every live entry to w from the least to the most
+// recently used, so Restore rebuilds the same order
+func (c *LRUCache) Snapshot(w io.Writer) error {
+	now := c.clock.Now()
+	var entries []Entry[string, any]
+	c.mu.RLock()
+	for el := c.order.Back(); el != nil; el = el.Prev() {
+		k := el.Value.(string)
+		item := c.items[k]
+		e := Entry[string, any]{Key: k, Value: item.obj, Expiration: item.expiration, Duration: item.duration}
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	c.mu.RUnlock()
+	return EncodeEntries(codecOr(c.codec).NewEncoder(w), entries)
+}
+
+// Restore load the entries of a snapshot, keeping their expiration.
+// Entries that expired in the meantime are skipped.
+func (c *LRUCache) Restore(r io.Reader) error {
+	return DecodeEntries(c.clock, codecOr(c.codec).NewDecoder(r), func(e *Entry[string, any]) {
+		c.set(e.Key, e.Value, e.Expiration, e.Duration)
+	})
+}
@@ -0,0 +1,111 @@
+package basic
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshot(t *testing.T) {
+	Convey("partition cache round trips with its ttl", t, func() {
+		cache := NewPartitionCache[string, []byte]()
+		defer cache.Close()
+		cache.SetWithExp("a", []byte("1"), time.Hour)
+		cache.SetWithExp("b", []byte("2"), time.Minute)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+
+		restored := NewPartitionCache[string, []byte](WithShards(2))
+		defer restored.Close()
+		So(restored.Restore(&buf), ShouldBeNil)
+		v, err := restored.Get("a")
+		So(err, ShouldBeNil)
+		So(string(v), ShouldEqual, "1")
+		h := ehash("b")
+		item := restored.table.Load().bucket(h).items[h]
+		So(item.duration, ShouldEqual, int64(time.Minute))
+		So(item.expiration, ShouldEqual, cache.table.Load().bucket(h).items[h].expiration)
+	})
+
+	Convey("restore skips entries that expired", t, func() {
+		var buf bytes.Buffer
+		enc := JSONCodec[string, int]{}.NewEncoder(&buf)
+		past := time.Now().Add(-time.Second).UnixNano()
+		future := time.Now().Add(time.Hour).UnixNano()
+		So(enc.Encode(&Entry[string, int]{Key: "old", Value: 1, Expiration: past, Duration: 1}), ShouldBeNil)
+		So(enc.Encode(&Entry[string, int]{Key: "new", Value: 2, Expiration: future, Duration: 1}), ShouldBeNil)
+
+		cache := NewTemplateCache[string, int]()
+		cache.WithCodec(JSONCodec[string, int]{})
+		So(cache.Restore(&buf), ShouldBeNil)
+		_, err := cache.Get("old")
+		So(err, ShouldEqual, NotFound)
+		v, err := cache.Get("new")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 2)
+	})
+
+	Convey("sieve cache keeps its queue order", t, func() {
+		cache := NewSieveCache[int, int](10)
+		for i := 0; i < 5; i++ {
+			cache.Set(i, i)
+		}
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewSieveCache[int, int](10)
+		So(restored.Restore(&buf), ShouldBeNil)
+		var order []int
+		for n := restored.tail; n != nil; n = n.prev {
+			order = append(order, n.k)
+		}
+		So(order, ShouldResemble, []int{0, 1, 2, 3, 4})
+	})
+
+	Convey("simple cache round trips with its ttl", t, func() {
+		cache := NewSimpleCache()
+		cache.SetWithExp("a", "1", time.Hour)
+		cache.SetWithExp("b", "2", time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewSimpleCache()
+		So(restored.Restore(&buf), ShouldBeNil)
+		v, err := restored.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "1")
+		So(restored.items["a"].expiration, ShouldEqual, cache.items["a"].expiration)
+		So(restored.Keys(), ShouldResemble, []string{"a"})
+	})
+
+	Convey("lru cache keeps its order with the json codec", t, func() {
+		cache := NewLRUCache(10)
+		defer cache.Close()
+		cache.WithCodec(JSONCodec[string, any]{})
+		for _, k := range []string{"a", "b", "c"} {
+			cache.Set(k, k)
+		}
+		cache.Get("a")
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewLRUCache(10)
+		defer restored.Close()
+		restored.WithCodec(JSONCodec[string, any]{})
+		So(restored.Restore(&buf), ShouldBeNil)
+		var order []string
+		for el := restored.order.Back(); el != nil; el = el.Prev() {
+			order = append(order, el.Value.(string))
+		}
+		So(order, ShouldResemble, []string{"b", "c", "a"})
+		v, err := restored.Get("c")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, "c")
+	})
+
+	Convey("a corrupt snapshot is an error", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.Restore(bytes.NewBufferString("not gob")), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,206 @@
+package basic
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexStripes is the number of locks of a KeyIndex, a power of two
+const indexStripes = 64
+
+// KeyIndex find the keys of a cache by tag, and by prefix for string keys,
+// across all its shards. Shards keep it up to date under their lock as
+// entries come and go. It is striped on the same hash as the shards, so
+// with up to indexStripes shards no two shards share a stripe and writes
+// never wait on another shard; a stripe is only locked for entries with
+// tags, or for every entry when the prefix index is on. Lookups merge the
+// stripes.
+type KeyIndex[K comparable] struct {
+	prefix  bool
+	stripes [indexStripes]indexStripe[K]
+}
+
+type indexStripe[K comparable] struct {
+	mu     sync.Mutex
+	tags   map[string]map[K]struct{}
+	prefix *radixTree
+}
+
+// NewKeyIndex new index, prefix keeps a radix tree of every string key
+func NewKeyIndex[K comparable](prefix bool) *KeyIndex[K] {
+	x := &KeyIndex[K]{}
+	_, str := any(*new(K)).(string)
+	x.prefix = str && prefix
+	for i := range x.stripes {
+		x.stripes[i].tags = make(map[string]map[K]struct{})
+		if x.prefix {
+			x.stripes[i].prefix = &radixTree{}
+		}
+	}
+	return x
+}
+
+func (x *KeyIndex[K]) stripe(h uintptr) *indexStripe[K] {
+	return &x.stripes[h&(indexStripes-1)]
+}
+
+// Insert record a new entry of hash h
+func (x *KeyIndex[K]) Insert(h uintptr, k K, tags []string) {
+	if x == nil || (!x.prefix && len(tags) == 0) {
+		return
+	}
+	s := x.stripe(h)
+	s.mu.Lock()
+	if s.prefix != nil {
+		s.prefix.insert(any(k).(string))
+	}
+	s.addTags(k, tags)
+	s.mu.Unlock()
+}
+
+// Update replace the tags of an entry of hash h
+func (x *KeyIndex[K]) Update(h uintptr, k K, old, tags []string) {
+	if x == nil || len(old)+len(tags) == 0 {
+		return
+	}
+	s := x.stripe(h)
+	s.mu.Lock()
+	s.removeTags(k, old)
+	s.addTags(k, tags)
+	s.mu.Unlock()
+}
+
+// Remove forget an entry of hash h that left the cache
+func (x *KeyIndex[K]) Remove(h uintptr, k K, tags []string) {
+	if x == nil || (!x.prefix && len(tags) == 0) {
+		return
+	}
+	s := x.stripe(h)
+	s.mu.Lock()
+	if s.prefix != nil {
+		s.prefix.remove(any(k).(string))
+	}
+	s.removeTags(k, tags)
+	s.mu.Unlock()
+}
+
+// Tag return the keys of tag
+func (x *KeyIndex[K]) Tag(tag string) []K {
+	var ks []K
+	for i := range x.stripes {
+		s := &x.stripes[i]
+		s.mu.Lock()
+		for k := range s.tags[tag] {
+			ks = append(ks, k)
+		}
+		s.mu.Unlock()
+	}
+	return ks
+}
+
+// Prefix return the keys starting with p, false when there is no prefix index
+func (x *KeyIndex[K]) Prefix(p string) ([]K, bool) {
+	if !x.prefix {
+		return nil, false
+	}
+	var ks []K
+	for i := range x.stripes {
+		s := &x.stripes[i]
+		s.mu.Lock()
+		s.prefix.walk(p, func(str string) {
+			ks = append(ks, any(str).(K))
+		})
+		s.mu.Unlock()
+	}
+	return ks, true
+}
+
+func (s *indexStripe[K]) addTags(k K, tags []string) {
+	for _, tag := range tags {
+		set := s.tags[tag]
+		if set == nil {
+			set = make(map[K]struct{})
+			s.tags[tag] = set
+		}
+		set[k] = struct{}{}
+	}
+}
+
+func (s *indexStripe[K]) removeTags(k K, tags []string) {
+	for _, tag := range tags {
+		set := s.tags[tag]
+		delete(set, k)
+		if len(set) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// HasTag report whether tags holds tag
+func HasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPrefix report whether k is a string starting with p
+func HasPrefix[K comparable](k K, p string) bool {
+	s, ok := any(k).(string)
+	return ok && strings.HasPrefix(s, p)
+}
+
+// SetWithTags set v with a ttl of dur and tag it for InvalidateTag. The
+// tags replace those of an earlier set; a plain Set drops them.
+func (c *PartitionCache[K, V]) SetWithTags(k K, v V, dur time.Duration, tags ...string) {
+	hash := ehash(k)
+	b := c.table.Load().bucket(hash)
+	b.set(k, v, hash, b.now()+int64(dur), int64(dur), tags, c.wal.Load())
+}
+
+// InvalidateTag delete every entry tagged with tag and return how many
+func (c *PartitionCache[K, V]) InvalidateTag(tag string) int {
+	n := 0
+	for _, k := range c.idx.Tag(tag) {
+		h := ehash(k)
+		if c.table.Load().bucket(h).delete(k, h, nil, OpDelete, func(i TemplateItem[K, V]) bool { return HasTag(i.tags, tag) }, c.wal.Load()) {
+			n++
+		}
+	}
+	return n
+}
+
+// InvalidatePrefix delete every string key starting with p and return how
+// many. Without WithPrefixIndex it walks every shard.
+func (c *PartitionCache[K, V]) InvalidatePrefix(p string) int {
+	ks, ok := c.idx.Prefix(p)
+	if !ok {
+		t := c.table.Load()
+		for i := range t.buckets {
+			ks = t.buckets[i].prefixed(ks, p)
+		}
+	}
+	n := 0
+	for _, k := range ks {
+		h := ehash(k)
+		if c.table.Load().bucket(h).delete(k, h, nil, OpDelete, nil, c.wal.Load()) {
+			n++
+		}
+	}
+	return n
+}
+
+// prefixed append the keys of the bucket starting with p to dst
+func (b *bucket[K, V]) prefixed(dst []K, p string) []K {
+	b.mu.RLock()
+	for _, item := range b.items {
+		if item.ns == nil && HasPrefix(item.k, p) {
+			dst = append(dst, item.k)
+		}
+	}
+	b.mu.RUnlock()
+	return dst
+}
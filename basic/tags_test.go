@@ -0,0 +1,120 @@
+package basic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTags(t *testing.T) {
+	Convey("invalidate tag drops the tagged entries of every shard", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(8))
+		defer cache.Close()
+		for i := 0; i < 50; i++ {
+			cache.SetWithTags(fmt.Sprintf("a%d", i), i, time.Minute, "tenant:a", "all")
+			cache.SetWithTags(fmt.Sprintf("b%d", i), i, time.Minute, "tenant:b", "all")
+		}
+		// a plain set drops the tags of a1
+		cache.Set("a1", 1)
+		So(cache.InvalidateTag("tenant:a"), ShouldEqual, 49)
+		_, err := cache.Get("a2")
+		So(err, ShouldEqual, NotFound)
+		v, err := cache.Get("a1")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		So(cache.InvalidateTag("tenant:a"), ShouldEqual, 0)
+		So(cache.InvalidateTag("all"), ShouldEqual, 50)
+		for i := range cache.idx.stripes {
+			So(cache.idx.stripes[i].tags, ShouldBeEmpty)
+		}
+	})
+
+	Convey("evicted entries leave the index", t, func() {
+		cache := NewPartitionCache[int, int](WithCapacity(4), WithShards(1), WithPrefixIndex())
+		defer cache.Close()
+		for i := 0; i < 20; i++ {
+			cache.SetWithTags(i, i, time.Minute, "t")
+		}
+		So(len(cache.idx.Tag("t")), ShouldEqual, 4)
+		So(cache.idx.prefix, ShouldBeFalse)
+	})
+
+	Convey("invalidate prefix works with and without the radix tree", t, func() {
+		for _, opts := range [][]Option{{WithShards(4)}, {WithShards(4), WithPrefixIndex()}} {
+			cache := NewPartitionCache[string, int](opts...)
+			defer cache.Close()
+			for i := 0; i < 20; i++ {
+				cache.Set(fmt.Sprintf("tenant:1:%d", i), i)
+				cache.Set(fmt.Sprintf("tenant:2:%d", i), i)
+			}
+			cache.Delete("tenant:1:0")
+			So(cache.InvalidatePrefix("tenant:1:"), ShouldEqual, 19)
+			So(cache.InvalidatePrefix("tenant:1:"), ShouldEqual, 0)
+			_, err := cache.Get("tenant:2:5")
+			So(err, ShouldBeNil)
+			cache.Resize(16)
+			So(cache.InvalidatePrefix("tenant:"), ShouldEqual, 20)
+			for i := range cache.idx.stripes {
+				if s := &cache.idx.stripes[i]; s.prefix != nil {
+					So(s.prefix.size, ShouldEqual, 0)
+				}
+			}
+		}
+	})
+
+	Convey("shards write to their own stripes of the index", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(8), WithPrefixIndex())
+		defer cache.Close()
+		for i := 0; i < 100; i++ {
+			cache.SetWithTags(fmt.Sprint("k", i), i, time.Minute, "t")
+		}
+		mask := cache.table.Load().mask
+		for i := range cache.idx.stripes {
+			for k := range cache.idx.stripes[i].tags["t"] {
+				So(ehash(k)&mask, ShouldEqual, uintptr(i)&mask)
+			}
+		}
+		So(cache.idx.Tag("t"), ShouldHaveLength, 100)
+		ks, ok := cache.idx.Prefix("k1")
+		So(ok, ShouldBeTrue)
+		So(ks, ShouldHaveLength, 11)
+	})
+
+	Convey("tags survive a snapshot and a restart on the log", t, func() {
+		cache := NewPartitionCache[string, int](WithShards(4))
+		defer cache.Close()
+		for i := 0; i < 10; i++ {
+			cache.SetWithTags(fmt.Sprint("a", i), i, time.Minute, "tenant:a")
+			cache.SetWithTags(fmt.Sprint("b", i), i, time.Minute, "tenant:b")
+		}
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewPartitionCache[string, int](WithShards(4))
+		defer restored.Close()
+		So(restored.Restore(&buf), ShouldBeNil)
+		So(restored.InvalidateTag("tenant:a"), ShouldEqual, 10)
+		So(restored.Len(), ShouldEqual, 10)
+
+		dir := t.TempDir()
+		w, err := OpenWAL[string, int](dir, nil)
+		So(err, ShouldBeNil)
+		logged := NewPartitionCache[string, int]()
+		defer logged.Close()
+		So(logged.WithWAL(w), ShouldBeNil)
+		logged.SetWithTags("a0", 0, time.Minute, "tenant:a")
+		So(w.Compact(), ShouldBeNil)
+		logged.SetWithTags("a1", 1, time.Minute, "tenant:a")
+		logged.SetWithTags("b0", 0, time.Minute, "tenant:b")
+		So(w.Close(), ShouldBeNil)
+		w, _ = OpenWAL[string, int](dir, nil)
+		defer w.Close()
+		restarted := NewPartitionCache[string, int]()
+		defer restarted.Close()
+		So(restarted.WithWAL(w), ShouldBeNil)
+		So(restarted.InvalidateTag("tenant:a"), ShouldEqual, 2)
+		So(restarted.Keys(), ShouldResemble, []string{"b0"})
+	})
+}
@@ -0,0 +1,156 @@
+package basic
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the time source of a cache: expirations are computed and
+// checked against it, and it runs the janitor
+type Clock interface {
+	// Now return the current time in unix nanoseconds
+	Now() int64
+	// Every call fn every d until stop is called
+	Every(d time.Duration, fn func()) (stop func())
+}
+
+// SystemClock is the wall clock, the default of every cache
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() int64 {
+	return time.Now().UnixNano()
+}
+
+func (systemClock) Every(d time.Duration, fn func()) func() {
+	ticker := time.NewTicker(d)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// CoarseClock is a Clock reading a timestamp that a background goroutine
+// refreshes every resolution, so Now is an atomic load instead of a call
+// into the system clock. Now lags the wall clock by up to resolution:
+// an entry set with a ttl d expires between d-resolution and
+// d+resolution after it was set, and the refresh window of Get moves by
+// as much. One clock can be shared by every cache of a process.
+type CoarseClock struct {
+	now        atomic.Int64
+	resolution time.Duration
+	stop       func()
+}
+
+// NewCoarseClock new clock refreshed every resolution, a millisecond
+// when resolution is not positive. Stop ends its goroutine.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	if resolution <= 0 {
+		resolution = time.Millisecond
+	}
+	c := &CoarseClock{resolution: resolution}
+	c.tick()
+	c.stop = SystemClock.Every(resolution, c.tick)
+	return c
+}
+
+func (c *CoarseClock) tick() {
+	c.now.Store(time.Now().UnixNano())
+}
+
+// Now return the time of the last refresh
+func (c *CoarseClock) Now() int64 {
+	return c.now.Load()
+}
+
+// Every call fn every d of the system clock
+func (c *CoarseClock) Every(d time.Duration, fn func()) func() {
+	return SystemClock.Every(d, fn)
+}
+
+// Resolution return the refresh period of the clock
+func (c *CoarseClock) Resolution() time.Duration {
+	return c.resolution
+}
+
+// Stop end the refresh, Now stays at its last value
+func (c *CoarseClock) Stop() {
+	c.stop()
+}
+
+// FakeClock is a Clock that only moves when told to, for tests. The
+// functions given to Every run on the goroutine of Advance.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    int64
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	every   int64
+	next    int64
+	fn      func()
+	stopped bool
+}
+
+// NewFakeClock new fake clock set at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start.UnixNano()}
+}
+
+// Now return the time of the clock
+func (c *FakeClock) Now() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Every call fn each time Advance moves the clock past a multiple of d
+func (c *FakeClock) Every(d time.Duration, fn func()) func() {
+	c.mu.Lock()
+	t := &fakeTimer{every: int64(d), next: c.now + int64(d), fn: fn}
+	c.timers = append(c.timers, t)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		t.stopped = true
+		c.mu.Unlock()
+	}
+}
+
+// Advance move the clock forward by d. Every timer due on the way fires
+// in order, with the clock set at its deadline, before Advance returns.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now + int64(d)
+	for {
+		var due *fakeTimer
+		for _, t := range c.timers {
+			if !t.stopped && t.next <= end && (due == nil || t.next < due.next) {
+				due = t
+			}
+		}
+		if due == nil {
+			break
+		}
+		c.now = due.next
+		due.next += due.every
+		c.mu.Unlock()
+		due.fn()
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
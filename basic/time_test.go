@@ -0,0 +1,248 @@
+package basic
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFakeClock(t *testing.T) {
+	Convey("the fake clock fires its timers in order while advancing", t, func() {
+		start := time.Unix(1000, 0)
+		clock := NewFakeClock(start)
+		So(clock.Now(), ShouldEqual, start.UnixNano())
+		var fired []time.Duration
+		stop := clock.Every(time.Second, func() {
+			fired = append(fired, time.Duration(clock.Now()-start.UnixNano()))
+		})
+		clock.Every(1500*time.Millisecond, func() {
+			fired = append(fired, -time.Duration(clock.Now()-start.UnixNano()))
+		})
+		clock.Advance(999 * time.Millisecond)
+		So(fired, ShouldBeEmpty)
+		clock.Advance(2 * time.Second)
+		So(fired, ShouldResemble, []time.Duration{time.Second, -1500 * time.Millisecond, 2 * time.Second})
+		So(clock.Now(), ShouldEqual, start.Add(2999*time.Millisecond).UnixNano())
+		stop()
+		clock.Advance(time.Second)
+		So(fired, ShouldResemble, []time.Duration{time.Second, -1500 * time.Millisecond, 2 * time.Second, -3 * time.Second})
+	})
+
+	Convey("a cache on a fake clock expires without sleeping", t, func() {
+		clock := NewFakeClock(time.Now())
+		cache := NewPartitionCache[string, int](WithClock(clock), WithShards(2))
+		defer cache.Close()
+		cache.SetWithExp("a", 1, 10*time.Second)
+		cache.Set("b", 2)
+		clock.Advance(9 * time.Second)
+		v, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		So(cache.Touch("b", 5*time.Second), ShouldBeTrue)
+		clock.Advance(1*time.Second + 1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		e, _ := cache.GetEntry("a")
+		So(e.Stale, ShouldBeTrue)
+		So(cache.Keys(), ShouldResemble, []string{"b"})
+		// the janitor runs on the fake clock as well
+		clock.Advance(time.Second)
+		_, err = cache.Peek("a")
+		So(err, ShouldEqual, NotFound)
+		clock.Advance(4 * time.Second)
+		So(cache.Len(), ShouldEqual, 0)
+		_, err = cache.Peek("b")
+		So(err, ShouldEqual, NotFound)
+	})
+}
+
+func TestClockRestore(t *testing.T) {
+	Convey("a cache on a fake clock restores its own snapshot", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewPartitionCache[string, int](WithClock(clock))
+		defer cache.Close()
+		cache.SetWithExp("a", 1, time.Hour)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewPartitionCache[string, int](WithClock(clock))
+		defer restored.Close()
+		So(restored.Restore(&buf), ShouldBeNil)
+		So(restored.Len(), ShouldEqual, 1)
+		e, err := restored.GetEntry("a")
+		So(err, ShouldBeNil)
+		So(e.Stale, ShouldBeFalse)
+		clock.Advance(time.Hour + 1)
+		e, _ = restored.GetEntry("a")
+		So(e.Stale, ShouldBeTrue)
+	})
+
+	Convey("a cache on a fake clock replays its own WAL", t, func() {
+		dir := t.TempDir()
+		clock := NewFakeClock(time.Unix(1000, 0))
+		w, _ := OpenWAL[string, int](dir, nil)
+		cache := NewPartitionCache[string, int](WithClock(clock))
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.SetWithExp("a", 1, time.Hour)
+		So(w.Close(), ShouldBeNil)
+
+		w, _ = OpenWAL[string, int](dir, nil)
+		defer w.Close()
+		restored := NewPartitionCache[string, int](WithClock(clock))
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		So(restored.Len(), ShouldEqual, 1)
+	})
+}
+
+func TestClockOfEveryCache(t *testing.T) {
+	Convey("the template cache expires on its clock", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewTemplateCache[string, int](WithClock(clock))
+		cache.SetWithExp("a", 1, 10*time.Second)
+		clock.Advance(10 * time.Second)
+		v, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		clock.Advance(1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Len(), ShouldEqual, 0)
+		So(cache.Keys(), ShouldBeEmpty)
+	})
+
+	Convey("the simple cache expires on its clock", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewSimpleCache(WithClock(clock))
+		cache.SetWithExp("a", 1, 10*time.Second)
+		clock.Advance(10 * time.Second)
+		_, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		clock.Advance(1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Len(), ShouldEqual, 0)
+	})
+
+	Convey("the sieve cache expires on its clock", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewSieveCache[string, int](10, WithClock(clock))
+		cache.SetWithExp("a", 1, 10*time.Second)
+		clock.Advance(10 * time.Second)
+		_, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		clock.Advance(1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Len(), ShouldEqual, 0)
+	})
+
+	Convey("the lru cache expires and runs its janitor on its clock", t, func() {
+		clock := NewFakeClock(time.Unix(1000, 0))
+		cache := NewLRUCache(10, WithClock(clock))
+		defer cache.Close()
+		cache.SetWithExp("a", 1, 10*time.Second)
+		clock.Advance(10 * time.Second)
+		_, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		clock.Advance(1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Len(), ShouldEqual, 0)
+		clock.Advance(time.Second)
+		cache.mu.RLock()
+		So(cache.items, ShouldBeEmpty)
+		cache.mu.RUnlock()
+	})
+}
+
+func TestCoarseClock(t *testing.T) {
+	Convey("the coarse clock lags the wall clock by its resolution", t, func() {
+		clock := NewCoarseClock(5 * time.Millisecond)
+		defer clock.Stop()
+		So(clock.Resolution(), ShouldEqual, 5*time.Millisecond)
+		before := clock.Now()
+		So(before, ShouldBeLessThanOrEqualTo, time.Now().UnixNano())
+		deadline := time.Now().Add(time.Second)
+		for clock.Now() == before && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		So(clock.Now(), ShouldBeGreaterThan, before)
+	})
+
+	Convey("a resolution that is not positive falls back to a millisecond", t, func() {
+		for _, res := range []time.Duration{0, -time.Second} {
+			clock := NewCoarseClock(res)
+			So(clock.Resolution(), ShouldEqual, time.Millisecond)
+			clock.Stop()
+		}
+	})
+
+	Convey("entries expire within a resolution of their ttl", t, func() {
+		clock := NewCoarseClock(10 * time.Millisecond)
+		defer clock.Stop()
+		cache := NewPartitionCache[string, int](WithClock(clock))
+		defer cache.Close()
+		set := time.Now()
+		cache.SetWithExp("a", 1, 100*time.Millisecond)
+		// never before ttl-resolution
+		time.Sleep(50 * time.Millisecond)
+		if time.Since(set) < 90*time.Millisecond {
+			_, err := cache.Get("a")
+			So(err, ShouldBeNil)
+		}
+		// and once the clock is past ttl+resolution, whatever the
+		// scheduling delays of its goroutine
+		deadline := time.Now().Add(5 * time.Second)
+		for clock.Now() <= set.Add(110*time.Millisecond).UnixNano() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		So(clock.Now(), ShouldBeGreaterThan, set.Add(110*time.Millisecond).UnixNano())
+		_, err := cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+	})
+}
+
+func TestJanitorRun(t *testing.T) {
+	Convey("Run calls del every interval until Stop", t, func() {
+		j := &Janitor{Interval: time.Millisecond}
+		var n atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			j.Run(func() { n.Add(1) })
+			close(done)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for n.Load() < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		So(n.Load(), ShouldBeGreaterThanOrEqualTo, 2)
+		j.Stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			So("Run did not return", ShouldBeEmpty)
+		}
+		j.Stop()
+	})
+}
+
+func BenchmarkClockNow(b *testing.B) {
+	coarse := NewCoarseClock(time.Millisecond)
+	defer coarse.Stop()
+	for _, c := range []struct {
+		name  string
+		clock Clock
+	}{{"system", SystemClock}, {"coarse", coarse}} {
+		b.Run(c.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					c.clock.Now()
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,614 @@
+package basic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is the kind of a WAL record
+type Op uint8
+
+const (
+	OpSet Op = iota + 1
+	OpDelete
+	OpExpire
+	// OpClear is InvalidateAll, its entry is empty
+	OpClear
+)
+
+// SyncPolicy decide when the WAL calls fsync
+type SyncPolicy int8
+
+const (
+	// SyncInterval write and fsync the log every WALOptions.Interval,
+	// a crash loses at most the writes of the last interval
+	SyncInterval SyncPolicy = iota
+	// SyncAlways make every write wait for its fsync. Concurrent writers
+	// share one fsync (group commit).
+	SyncAlways
+	// SyncNever write the log every interval and leave fsync to the OS
+	SyncNever
+)
+
+// WALOptions holds the settings of a WAL
+type WALOptions struct {
+	Sync SyncPolicy
+	// Interval is how often pending records are written out
+	Interval time.Duration
+	// SegmentSize is the size after which the log moves to a new segment
+	SegmentSize int64
+	// CompactAfter folds the log into a snapshot once that many segments
+	// were written since the last one, 0 only compacts on Compact
+	CompactAfter int
+}
+
+// WALOption configures a WAL
+type WALOption func(*WALOptions)
+
+// WithSync set the fsync policy, SyncInterval by default
+func WithSync(p SyncPolicy) WALOption {
+	return func(o *WALOptions) {
+		o.Sync = p
+	}
+}
+
+// WithSyncInterval set how often pending records are written out, 100ms
+// by default and when d is not positive
+func WithSyncInterval(d time.Duration) WALOption {
+	return func(o *WALOptions) {
+		o.Interval = d
+	}
+}
+
+// WithSegmentSize set the size of a log segment, 64MB by default
+func WithSegmentSize(n int64) WALOption {
+	return func(o *WALOptions) {
+		o.SegmentSize = n
+	}
+}
+
+// WithCompactAfter compact the log every n segments
+func WithCompactAfter(n int) WALOption {
+	return func(o *WALOptions) {
+		o.CompactAfter = n
+	}
+}
+
+const (
+	segExt  = ".log"
+	snapExt = ".snap"
+	// a record is its length, the crc32 of op and payload, op, payload
+	frameHeader = 9
+	// pending bytes that wake the writer before the interval is up
+	flushSize = 1 << 20
+)
+
+var (
+	errAttached    = errors.New("wal already attached")
+	errNotAttached = errors.New("wal not attached")
+	errClosed      = errors.New("wal closed")
+)
+
+// walBatch is pending records of one segment
+type walBatch struct {
+	seg uint64
+	buf []byte
+}
+
+// WAL is an append-only log of the writes of a cache. Records are encoded
+// with the codec into numbered segment files; Compact folds the segments
+// into a snapshot of the cache. A restart replays the last snapshot and
+// the segments written after it.
+//
+// The first write error stops logging, it is returned by Err, Sync and Close.
+type WAL[K comparable, V any] struct {
+	dir      string
+	o        WALOptions
+	codec    Codec[K, V]
+	snapshot func(io.Writer) error
+
+	mu      sync.Mutex
+	cond    sync.Cond
+	seg     uint64
+	segSize int64
+	scratch bytes.Buffer
+	enc     Encoder[K, V]
+	pending []walBatch
+	seq     uint64
+	synced  uint64
+	base    uint64
+	closed  bool
+	err     error
+
+	compactMu  sync.Mutex
+	compacting atomic.Bool
+	compactWG  sync.WaitGroup
+
+	file    *os.File
+	fileSeg uint64
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// OpenWAL open the log in dir, creating it when needed. The codec
+// encodes the records, nil is gob. Nothing is read before a cache
+// attaches with WithWAL.
+func OpenWAL[K comparable, V any](dir string, codec Codec[K, V], opts ...WALOption) (*WAL[K, V], error) {
+	o := WALOptions{
+		Interval:    100 * time.Millisecond,
+		SegmentSize: 64 << 20,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Interval <= 0 {
+		o.Interval = 100 * time.Millisecond
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	w := &WAL[K, V]{
+		dir:   dir,
+		o:     o,
+		codec: codecOr(codec),
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	w.cond.L = &w.mu
+	return w, nil
+}
+
+// Attach replay the last snapshot and the log, then start logging.
+// restore loads a snapshot, apply replays one record; a set that expired
+// by clock in the meantime is replayed as OpExpire. snapshot is used by
+// Compact. The caches call it from WithWAL with their clock.
+func (w *WAL[K, V]) Attach(clock Clock, snapshot func(io.Writer) error, restore func(io.Reader) error, apply func(Op, *Entry[K, V])) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errClosed
+	}
+	if w.snapshot != nil {
+		return errAttached
+	}
+	snaps, segs, err := w.files()
+	if err != nil {
+		return err
+	}
+	if len(snaps) > 0 {
+		w.base = snaps[len(snaps)-1]
+		if err := w.restore(w.base, restore); err != nil {
+			return err
+		}
+	}
+	w.seg = w.base
+	for _, s := range segs {
+		if s < w.base {
+			continue
+		}
+		if err := w.replay(clock, s, apply); err != nil {
+			return err
+		}
+		w.seg = s + 1
+	}
+	w.removeBefore(w.base)
+	w.enc = w.codec.NewEncoder(&w.scratch)
+	w.snapshot = snapshot
+	go w.run()
+	return nil
+}
+
+// Append log a record and return its sequence number for Wait. Caches
+// call it under the shard lock, so the records of a key are logged in
+// the order they were applied. A nil WAL does nothing.
+func (w *WAL[K, V]) Append(op Op, e *Entry[K, V]) uint64 {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.snapshot == nil || w.closed || w.err != nil {
+		return 0
+	}
+	if w.segSize >= w.o.SegmentSize {
+		w.rotate()
+		if w.o.CompactAfter > 0 && w.seg-w.base >= uint64(w.o.CompactAfter) && w.compacting.CompareAndSwap(false, true) {
+			w.compactWG.Add(1)
+			go w.autoCompact()
+		}
+	}
+	w.scratch.Reset()
+	w.scratch.Write(make([]byte, frameHeader))
+	if err := w.enc.Encode(e); err != nil {
+		w.err = err
+		return 0
+	}
+	frame := w.scratch.Bytes()
+	frame[8] = byte(op)
+	binary.LittleEndian.PutUint32(frame[0:], uint32(len(frame)-frameHeader))
+	binary.LittleEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(frame[8:]))
+	if len(w.pending) == 0 || w.pending[len(w.pending)-1].seg != w.seg {
+		w.pending = append(w.pending, walBatch{seg: w.seg})
+	}
+	b := &w.pending[len(w.pending)-1]
+	b.buf = append(b.buf, frame...)
+	w.segSize += int64(len(frame))
+	w.seq++
+	if w.o.Sync == SyncAlways || len(b.buf) >= flushSize {
+		w.signal()
+	}
+	return w.seq
+}
+
+// Wait block until record seq is on disk when the policy is SyncAlways.
+// Records appended in the meantime are committed with the same fsync.
+func (w *WAL[K, V]) Wait(seq uint64) error {
+	if w == nil || w.o.Sync != SyncAlways {
+		return nil
+	}
+	return w.wait(seq)
+}
+
+// Sync write out every record appended so far, with an fsync unless the
+// policy is SyncNever
+func (w *WAL[K, V]) Sync() error {
+	w.mu.Lock()
+	seq := w.seq
+	w.mu.Unlock()
+	w.signal()
+	return w.wait(seq)
+}
+
+// Err return the error that stopped logging, if any
+func (w *WAL[K, V]) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Compact fold the log into a snapshot: appends move on to a new segment,
+// the cache is snapshotted and the older segments are removed. Writers
+// are only held up by the snapshot of one shard at a time.
+func (w *WAL[K, V]) Compact() error {
+	w.compactMu.Lock()
+	defer w.compactMu.Unlock()
+	w.mu.Lock()
+	closed, attached := w.closed, w.snapshot != nil
+	w.mu.Unlock()
+	if closed {
+		return errClosed
+	}
+	if !attached {
+		return errNotAttached
+	}
+	return w.compact()
+}
+
+// compact is Compact, compactMu must be held
+func (w *WAL[K, V]) compact() error {
+	w.mu.Lock()
+	w.rotate()
+	base := w.seg
+	w.mu.Unlock()
+	// everything before base has to be in its segment before those go away
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	if err := w.writeSnapshot(base); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.base = base
+	w.mu.Unlock()
+	w.removeBefore(base)
+	return nil
+}
+
+// Close write out the pending records and stop logging
+func (w *WAL[K, V]) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.err
+	}
+	w.closed = true
+	attached := w.snapshot != nil
+	w.mu.Unlock()
+	// a running compaction still needs the writer
+	w.compactWG.Wait()
+	w.compactMu.Lock()
+	w.compactMu.Unlock()
+	if attached {
+		close(w.stop)
+		<-w.done
+	}
+	err := w.closeFile()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+func (w *WAL[K, V]) autoCompact() {
+	defer w.compactWG.Done()
+	defer w.compacting.Store(false)
+	w.compactMu.Lock()
+	err := w.compact()
+	w.compactMu.Unlock()
+	if err != nil {
+		w.mu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.mu.Unlock()
+	}
+}
+
+// rotate start a new segment, the mutex must be held
+func (w *WAL[K, V]) rotate() {
+	w.seg++
+	w.segSize = 0
+	w.enc = w.codec.NewEncoder(&w.scratch)
+}
+
+func (w *WAL[K, V]) signal() {
+	select {
+	case w.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (w *WAL[K, V]) wait(seq uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.synced < seq && w.err == nil {
+		w.cond.Wait()
+	}
+	return w.err
+}
+
+// run is the writer: it takes all pending records at once, so every
+// writer waiting on SyncAlways shares its fsync
+func (w *WAL[K, V]) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.o.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.kick:
+		case <-ticker.C:
+		case <-w.stop:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+func (w *WAL[K, V]) flush() {
+	w.mu.Lock()
+	batches, seq := w.pending, w.seq
+	w.pending = nil
+	w.mu.Unlock()
+	var err error
+	for _, b := range batches {
+		if err = w.write(b); err != nil {
+			break
+		}
+	}
+	if err == nil && len(batches) > 0 && w.o.Sync != SyncNever {
+		err = w.file.Sync()
+	}
+	w.mu.Lock()
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	w.synced = seq
+	w.cond.Broadcast()
+	w.mu.Unlock()
+}
+
+func (w *WAL[K, V]) write(b walBatch) error {
+	if w.file == nil || w.fileSeg != b.seg {
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(w.path(b.seg, segExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		w.file, w.fileSeg = f, b.seg
+	}
+	_, err := w.file.Write(b.buf)
+	return err
+}
+
+func (w *WAL[K, V]) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	var err error
+	if w.o.Sync != SyncNever {
+		err = w.file.Sync()
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
+
+// writeSnapshot write the snapshot covering the segments before base,
+// it only replaces the old one once it is complete
+func (w *WAL[K, V]) writeSnapshot(base uint64) error {
+	name := w.path(base, snapExt)
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	err = w.snapshot(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(name+".tmp", name)
+	}
+	if err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	return syncDir(w.dir)
+}
+
+func (w *WAL[K, V]) restore(base uint64, restore func(io.Reader) error) error {
+	f, err := os.Open(w.path(base, snapExt))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return restore(bufio.NewReader(f))
+}
+
+// replay apply the records of one segment, a set expired by clock as
+// OpExpire. A torn or corrupt record ends the segment: it can only be the
+// tail that was being written in a crash.
+func (w *WAL[K, V]) replay(clock Clock, seg uint64, apply func(Op, *Entry[K, V])) error {
+	data, err := os.ReadFile(w.path(seg, segExt))
+	if err != nil {
+		return err
+	}
+	now := clock.Now()
+	var in bytes.Buffer
+	dec := w.codec.NewDecoder(&in)
+	for len(data) >= frameHeader {
+		n := int(binary.LittleEndian.Uint32(data[0:]))
+		if len(data)-frameHeader < n {
+			break
+		}
+		frame := data[8 : frameHeader+n]
+		if crc32.ChecksumIEEE(frame) != binary.LittleEndian.Uint32(data[4:]) {
+			break
+		}
+		data = data[frameHeader+n:]
+		in.Write(frame[1:])
+		var e Entry[K, V]
+		if err := dec.Decode(&e); err != nil {
+			return fmt.Errorf("wal segment %d: %w", seg, err)
+		}
+		op := Op(frame[0])
+		if op == OpSet && e.Expired(now) {
+			op = OpExpire
+		}
+		apply(op, &e)
+	}
+	return nil
+}
+
+// files list the snapshots and segments in dir, oldest first
+func (w *WAL[K, V]) files() (snaps, segs []uint64, err error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(name)
+		if ext != segExt && ext != snapExt {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 16, 64)
+		if err != nil {
+			continue
+		}
+		if ext == segExt {
+			segs = append(segs, n)
+		} else {
+			snaps = append(snaps, n)
+		}
+	}
+	less := func(s []uint64) func(i, j int) bool {
+		return func(i, j int) bool { return s[i] < s[j] }
+	}
+	sort.Slice(snaps, less(snaps))
+	sort.Slice(segs, less(segs))
+	return snaps, segs, nil
+}
+
+// removeBefore remove the segments and snapshots a snapshot at base made obsolete
+func (w *WAL[K, V]) removeBefore(base uint64) {
+	snaps, segs, err := w.files()
+	if err != nil {
+		return
+	}
+	for _, s := range segs {
+		if s < base {
+			os.Remove(w.path(s, segExt))
+		}
+	}
+	for _, s := range snaps {
+		if s < base {
+			os.Remove(w.path(s, snapExt))
+		}
+	}
+}
+
+func (w *WAL[K, V]) path(n uint64, ext string) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%016x%s", n, ext))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// WithWAL replay the snapshot and the log of w into the cache, then log
+// every set and delete to it. Call it before the cache is used; snapshots
+// of the log are written with the codec of WithCodec.
+func (c *PartitionCache[K, V]) WithWAL(w *WAL[K, V]) error {
+	err := w.Attach(c.stamps.clock, c.Snapshot, c.Restore, func(op Op, e *Entry[K, V]) {
+		if op == OpClear {
+			c.InvalidateAll()
+			return
+		}
+		h := ehash(e.Key)
+		b := c.table.Load().bucket(h)
+		if op == OpSet {
+			b.set(e.Key, e.Value, h, e.Expiration, e.Duration, e.Tags, nil)
+		} else {
+			b.delete(e.Key, h, nil, op, nil, nil)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	c.wal.Store(w)
+	return nil
+}
@@ -0,0 +1,211 @@
+package basic
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWAL(t *testing.T) {
+	Convey("a restart replays sets and deletes", t, func() {
+		dir := t.TempDir()
+		w, err := OpenWAL[string, int](dir, nil)
+		So(err, ShouldBeNil)
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.SetWithExp("a", 1, time.Hour)
+		cache.Set("b", 2)
+		cache.Set("a", 3)
+		cache.Delete("b")
+		So(w.Close(), ShouldBeNil)
+
+		w, err = OpenWAL[string, int](dir, nil)
+		So(err, ShouldBeNil)
+		restored := NewPartitionCache[string, int]()
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		v, err := restored.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 3)
+		_, err = restored.Get("b")
+		So(err, ShouldEqual, NotFound)
+		h := ehash("a")
+		So(restored.table.Load().bucket(h).items[h].expiration, ShouldEqual, cache.table.Load().bucket(h).items[h].expiration)
+	})
+
+	Convey("a restart replays invalidate all", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[string, int](dir, nil)
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.Set("a", 1)
+		cache.InvalidateAll()
+		cache.Set("b", 2)
+		So(w.Close(), ShouldBeNil)
+
+		w, _ = OpenWAL[string, int](dir, nil)
+		restored := NewPartitionCache[string, int]()
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		_, err := restored.Get("a")
+		So(err, ShouldEqual, NotFound)
+		v, _ := restored.Get("b")
+		So(v, ShouldEqual, 2)
+	})
+
+	Convey("a set that expired before the restart hides the older value", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[string, int](dir, JSONCodec[string, int]{})
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.SetWithExp("a", 1, time.Hour)
+		cache.SetWithExp("a", 2, time.Millisecond)
+		So(w.Close(), ShouldBeNil)
+		time.Sleep(2 * time.Millisecond)
+
+		w, _ = OpenWAL[string, int](dir, JSONCodec[string, int]{})
+		restored := NewPartitionCache[string, int]()
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		_, err := restored.Get("a")
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("sync always commits concurrent writers together", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[int, int](dir, nil, WithSync(SyncAlways))
+		cache := NewPartitionCache[int, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					cache.Set(g*100+i, i)
+				}
+			}(g)
+		}
+		wg.Wait()
+		// every set returned after its fsync, nothing is pending
+		w.mu.Lock()
+		So(w.synced, ShouldEqual, w.seq)
+		So(w.seq, ShouldEqual, 400)
+		w.mu.Unlock()
+		So(w.Close(), ShouldBeNil)
+	})
+
+	Convey("compaction folds the segments into a snapshot", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[int, int](dir, nil, WithSegmentSize(256))
+		cache := NewPartitionCache[int, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		for i := 0; i < 100; i++ {
+			cache.Set(i, i)
+		}
+		So(w.Sync(), ShouldBeNil)
+		_, segs, _ := w.files()
+		So(len(segs), ShouldBeGreaterThan, 1)
+		So(w.Compact(), ShouldBeNil)
+		cache.Set(100, 100)
+		cache.Delete(0)
+		So(w.Close(), ShouldBeNil)
+		snaps, segs, _ := w.files()
+		So(snaps, ShouldHaveLength, 1)
+		for _, s := range segs {
+			So(s, ShouldBeGreaterThanOrEqualTo, snaps[0])
+		}
+
+		w, _ = OpenWAL[int, int](dir, nil)
+		restored := NewPartitionCache[int, int]()
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		for i := 1; i <= 100; i++ {
+			v, err := restored.Get(i)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, i)
+		}
+		_, err := restored.Get(0)
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("compaction runs by itself every few segments", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[int, int](dir, nil, WithSegmentSize(128), WithCompactAfter(2))
+		cache := NewPartitionCache[int, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		for i := 0; i < 200; i++ {
+			cache.Set(i, i)
+		}
+		So(w.Close(), ShouldBeNil)
+		snaps, _, _ := w.files()
+		So(len(snaps), ShouldBeGreaterThan, 0)
+	})
+
+	Convey("a torn tail is dropped", t, func() {
+		dir := t.TempDir()
+		w, _ := OpenWAL[string, int](dir, nil)
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		So(w.Close(), ShouldBeNil)
+		_, segs, _ := w.files()
+		name := w.path(segs[0], segExt)
+		data, _ := os.ReadFile(name)
+		So(os.WriteFile(name, data[:len(data)-3], 0o644), ShouldBeNil)
+
+		w, _ = OpenWAL[string, int](dir, nil)
+		restored := NewPartitionCache[string, int]()
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		v, err := restored.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		_, err = restored.Get("b")
+		So(err, ShouldEqual, NotFound)
+		// new records go to a new segment after the torn one
+		restored.Set("c", 3)
+		So(w.Close(), ShouldBeNil)
+		matches, _ := filepath.Glob(filepath.Join(dir, "*"+segExt))
+		So(matches, ShouldHaveLength, 2)
+	})
+
+	Convey("a wal only attaches once", t, func() {
+		w, _ := OpenWAL[string, int](t.TempDir(), nil)
+		first, second := NewPartitionCache[string, int](), NewPartitionCache[string, int]()
+		defer first.Close()
+		defer second.Close()
+		So(first.WithWAL(w), ShouldBeNil)
+		So(second.WithWAL(w), ShouldEqual, errAttached)
+		So(w.Close(), ShouldBeNil)
+		So(w.Compact(), ShouldEqual, errClosed)
+	})
+	Convey("an interval that is not positive falls back to the default", t, func() {
+		for _, d := range []time.Duration{0, -time.Second} {
+			w, err := OpenWAL[string, int](t.TempDir(), nil, WithSyncInterval(d))
+			So(err, ShouldBeNil)
+			So(w.o.Interval, ShouldEqual, 100*time.Millisecond)
+			cache := NewPartitionCache[string, int]()
+			defer cache.Close()
+			So(cache.WithWAL(w), ShouldBeNil)
+			cache.Set("a", 1)
+			So(w.Close(), ShouldBeNil)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package basic
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WarmupOptions holds the settings of a warmup
+type WarmupOptions struct {
+	// Concurrency is the number of keys loaded at the same time
+	Concurrency int
+	// Rate is the most loads per second, 0 is no limit
+	Rate float64
+	// Spread shortens every ttl by a random share of up to Spread, so the
+	// warmed keys do not all expire at the same time
+	Spread float64
+	// Progress is called after every key, one call at a time
+	Progress func(WarmupProgress)
+}
+
+// WarmupOption configures a warmup
+type WarmupOption func(*WarmupOptions)
+
+// WithConcurrency set the number of concurrent loads, GOMAXPROCS by default
+func WithConcurrency(n int) WarmupOption {
+	return func(o *WarmupOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithRate limit the loads per second
+func WithRate(perSecond float64) WarmupOption {
+	return func(o *WarmupOptions) {
+		o.Rate = perSecond
+	}
+}
+
+// WithTTLSpread shorten every ttl by a random share of up to f, 0 <= f < 1
+func WithTTLSpread(f float64) WarmupOption {
+	return func(o *WarmupOptions) {
+		o.Spread = f
+	}
+}
+
+// WithProgress set the progress callback
+func WithProgress(fn func(WarmupProgress)) WarmupOption {
+	return func(o *WarmupOptions) {
+		o.Progress = fn
+	}
+}
+
+// WarmupProgress count the keys of a warmup
+type WarmupProgress struct {
+	Total  int
+	Done   int
+	Failed int
+}
+
+// WarmupReport is the outcome of a warmup, Errors holds the loader error
+// of every key that failed
+type WarmupReport[K comparable] struct {
+	WarmupProgress
+	Errors map[K]error
+}
+
+// Warmup load every key of ks and set it with a ttl of dur. It returns
+// once all keys are done, or with ctx.Err() when ctx is done first: the
+// report then counts the keys done so far.
+func Warmup[K comparable, V any](ctx context.Context, ks []K, load func(K) (V, error), set func(K, V, time.Duration), dur time.Duration, opts ...WarmupOption) (WarmupReport[K], error) {
+	o := WarmupOptions{Concurrency: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	r := WarmupReport[K]{Errors: make(map[K]error)}
+	r.Total = len(ks)
+	lim := newLimiter(o.Rate)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	keys := make(chan K)
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range keys {
+				if lim.wait(ctx) != nil {
+					continue
+				}
+				v, err := load(k)
+				if err == nil {
+					set(k, v, spread(dur, o.Spread))
+				}
+				mu.Lock()
+				r.Done++
+				if err != nil {
+					r.Failed++
+					r.Errors[k] = err
+				}
+				if o.Progress != nil {
+					o.Progress(r.WarmupProgress)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, k := range ks {
+		select {
+		case keys <- k:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(keys)
+	wg.Wait()
+	return r, ctx.Err()
+}
+
+// spread shorten dur by a random share of up to f
+func spread(dur time.Duration, f float64) time.Duration {
+	if f <= 0 {
+		return dur
+	}
+	return dur - time.Duration(rand.Float64()*f*float64(dur))
+}
+
+// limiter hand out evenly spaced slots, a nil limiter has no limit
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait sleep until the next slot, or until ctx is done
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	d := time.Until(at)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Warm load ks with the callback, see Warmup
+func (c *TemplateCache[K, V]) Warm(ctx context.Context, ks []K, opts ...WarmupOption) (WarmupReport[K], error) {
+	if c.caller == nil {
+		return WarmupReport[K]{}, NoCallback
+	}
+	return Warmup(ctx, ks, c.caller, c.SetWithExp, c.defaultDuration, opts...)
+}
+
+// Warm load ks with the callback, see Warmup
+func (c *PartitionCache[K, V]) Warm(ctx context.Context, ks []K, opts ...WarmupOption) (WarmupReport[K], error) {
+	if c.caller == nil {
+		return WarmupReport[K]{}, NoCallback
+	}
+	return Warmup(ctx, ks, c.caller, c.SetWithExp, c.defaultDuration, opts...)
+}
+
+// Warm load ks with the callback, see Warmup
+func (c *SimpleCache) Warm(ctx context.Context, ks []string, opts ...WarmupOption) (WarmupReport[string], error) {
+	if c.caller == nil {
+		return WarmupReport[string]{}, NoCallback
+	}
+	return Warmup(ctx, ks, c.caller, c.SetWithExp, c.defaultDuration, opts...)
+}
+
+// Warm load ks with the callback, see Warmup
+func (c *LRUCache) Warm(ctx context.Context, ks []string, opts ...WarmupOption) (WarmupReport[string], error) {
+	if c.caller == nil {
+		return WarmupReport[string]{}, NoCallback
+	}
+	return Warmup(ctx, ks, c.caller, c.SetWithExp, c.defaultDuration, opts...)
+}
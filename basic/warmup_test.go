@@ -0,0 +1,93 @@
+package basic
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWarmup(t *testing.T) {
+	ctx := context.Background()
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	Convey("warm loads every key concurrently and reports failures", t, func() {
+		var running, peak atomic.Int32
+		boom := errors.New("boom")
+		cache := NewTemplateCache[string, int]()
+		cache.WithCallback(func(k string) (int, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(time.Millisecond)
+			if k == "13" {
+				return 0, boom
+			}
+			return strconv.Atoi(k)
+		})
+		var last WarmupProgress
+		r, err := cache.Warm(ctx, keys, WithConcurrency(8), WithProgress(func(p WarmupProgress) { last = p }))
+		So(err, ShouldBeNil)
+		So(r.Total, ShouldEqual, 100)
+		So(r.Done, ShouldEqual, 100)
+		So(r.Failed, ShouldEqual, 1)
+		So(r.Errors, ShouldResemble, map[string]error{"13": boom})
+		So(last, ShouldResemble, r.WarmupProgress)
+		So(peak.Load(), ShouldBeBetweenOrEqual, 2, 8)
+		v, err := cache.Get("42")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 42)
+	})
+
+	Convey("the rate limit spaces the loads", t, func() {
+		cache := NewPartitionCache[string, int]()
+		defer cache.Close()
+		cache.WithCallback(func(k string) (int, error) { return 1, nil })
+		start := time.Now()
+		r, err := cache.Warm(ctx, keys[:11], WithRate(500))
+		So(err, ShouldBeNil)
+		So(r.Done, ShouldEqual, 11)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 20*time.Millisecond)
+	})
+
+	Convey("a cancelled warmup stops early", t, func() {
+		cctx, cancel := context.WithCancel(ctx)
+		cache := NewSimpleCache()
+		cache.WithCallback(func(k string) (any, error) {
+			if k == "5" {
+				cancel()
+			}
+			return k, nil
+		})
+		r, err := cache.Warm(cctx, keys, WithConcurrency(1))
+		So(err, ShouldEqual, context.Canceled)
+		So(r.Done, ShouldBeLessThan, 100)
+	})
+
+	Convey("ttls are spread below the default", t, func() {
+		var durs []time.Duration
+		set := func(k string, v int, d time.Duration) { durs = append(durs, d) }
+		_, err := Warmup(ctx, keys, func(string) (int, error) { return 0, nil }, set, time.Minute,
+			WithConcurrency(1), WithTTLSpread(0.5))
+		So(err, ShouldBeNil)
+		distinct := map[time.Duration]bool{}
+		for _, d := range durs {
+			So(d, ShouldBeBetweenOrEqual, 30*time.Second, time.Minute)
+			distinct[d] = true
+		}
+		So(len(distinct), ShouldBeGreaterThan, 50)
+	})
+
+	Convey("warm needs a callback", t, func() {
+		_, err := NewLRUCache(10).Warm(ctx, keys)
+		So(err, ShouldEqual, NoCallback)
+	})
+}
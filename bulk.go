@@ -0,0 +1,68 @@
+package stablecache
+
+import (
+	"context"
+	"stablecache/basic"
+)
+
+// WithBulkLoader set the loader GetMany hands its misses to
+func (c *LRUCache[K, V]) WithBulkLoader(call func(context.Context, []K) (map[K]V, error)) {
+	c.bulk = basic.NewBulkLoader(call, c.options.LoadBatch, func(k K, v V) {
+		c.SetWithExp(k, v, c.defaultDuration)
+	})
+}
+
+// GetMany return the cached values of ks and load the rest: all at once
+// with the bulk loader, or one by one with the callback when there is no
+// bulk loader. Expired entries are loaded again. Keys that are neither
+// cached nor loaded are left out of the result. A key Get is loading at
+// the same time may be loaded again by the bulk loader.
+func (c *LRUCache[K, V]) GetMany(ctx context.Context, ks []K) (map[K]V, error) {
+	r := make(map[K]V, len(ks))
+	var misses []K
+	for _, k := range ks {
+		h := ehash(k)
+		if v, ok := c.table.Load().bucket(h).hit(k, h); ok {
+			r[k] = v
+		} else {
+			misses = append(misses, k)
+		}
+	}
+	if len(misses) == 0 {
+		return r, nil
+	}
+	if c.bulk == nil {
+		for _, k := range misses {
+			if err := ctx.Err(); err != nil {
+				return r, err
+			}
+			if v, err := c.Get(k); err == nil {
+				r[k] = v
+			}
+		}
+		return r, nil
+	}
+	loaded, err := c.bulk.Load(ctx, misses)
+	for k, v := range loaded {
+		r[k] = v
+	}
+	return r, err
+}
+
+// hit return the value of k when it is cached and not expired, and
+// record the access like Get
+func (b *LRUBucket[K, V]) hit(k K, h uintptr) (r V, ok bool) {
+	b = b.rlock(h)
+	item, ok := b.items[h]
+	ok = ok && item.owned(k, nil, b.stamps.epoch.Load()) && !item.expiredAt(b.now())
+	touched := ok && b.policy.touch(item.p)
+	b.mu.RUnlock()
+	if !ok {
+		return r, false
+	}
+	item.p.hits.Add(1)
+	if !touched {
+		b.record(item.p)
+	}
+	return item.obj, true
+}
@@ -0,0 +1,33 @@
+package stablecache
+
+import (
+	"context"
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUGetMany(t *testing.T) {
+	Convey("lru get many loads every miss in one call", t, func() {
+		calls := 0
+		cache := NewLRUCache[string, int](100, basic.WithLoadBatch(10))
+		cache.WithBulkLoader(func(ctx context.Context, ks []string) (map[string]int, error) {
+			calls++
+			r := make(map[string]int)
+			for _, k := range ks {
+				r[k] = len(k)
+			}
+			return r, nil
+		})
+		cache.Set("a", 9)
+		r, err := cache.GetMany(context.Background(), []string{"a", "bb", "ccc"})
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, map[string]int{"a": 9, "bb": 2, "ccc": 3})
+		So(calls, ShouldEqual, 1)
+		r, _ = cache.GetMany(context.Background(), []string{"bb", "ccc"})
+		So(r, ShouldHaveLength, 2)
+		So(calls, ShouldEqual, 1)
+	})
+}
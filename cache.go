@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"stablecache/basic"
+	"sync"
 	"time"
 )
 
@@ -23,18 +24,31 @@ type noCopy struct{}
 
 func (*noCopy) Lock() {}
 
-type Type int8
+type Type = basic.Type
 
 const (
-	LRU Type = iota
-	LFU      = iota
-	ARC      = iota
+	LRU      = basic.LRU
+	LFU      = basic.LFU
+	ARC      = basic.ARC
+	WTinyLFU = basic.WTinyLFU
+	S3FIFO   = basic.S3FIFO
+	TwoQ     = basic.TwoQ
+	SLRU     = basic.SLRU
+)
+
+type Action = basic.Action
+
+const (
+	Keep   = basic.Keep
+	Update = basic.Update
+	Remove = basic.Remove
 )
 
 var (
 	NotFound = errors.New("not found")
 	Timeout  = errors.New("timeout")
 	Disuse   = errors.New("disuse")
+	Closed   = errors.New("closed")
 )
 
 type Cache[K comparable, V any] interface {
@@ -43,6 +57,8 @@ type Cache[K comparable, V any] interface {
 	Get(K) (V, error)
 	Set(K, V)
 	SetWithExp(K, V, time.Duration)
+	// Close stop the janitor of the cache
+	Close()
 }
 
 func randfunc(t, d int64) bool {
@@ -74,32 +90,60 @@ func New[K comparable, V any](t string) Cache[K, V] {
 
 type Janitor struct {
 	Interval time.Duration
-	stop     chan bool
+	clock    basic.Clock
+	stop     func()
+	mu       sync.Mutex
+	done     chan struct{}
 }
 
+// Run call del every Interval of the clock of j, the system clock for a
+// Janitor built by hand, until Stop
 func (j *Janitor) Run(del func()) {
-	ticker := time.NewTicker(j.Interval)
-	for {
-		select {
-		case <-ticker.C:
-			del()
-		case <-j.stop:
-			ticker.Stop()
-			return
-		}
+	clock := j.clock
+	if clock == nil {
+		clock = basic.SystemClock
+	}
+	stop := clock.Every(j.Interval, del)
+	<-j.stopped()
+	stop()
+}
+
+func (j *Janitor) stopped() chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done == nil {
+		j.done = make(chan struct{})
 	}
+	return j.done
 }
 
 func (j *Janitor) Stop() {
 	fmt.Println("janitor stop")
-	j.stop <- true
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done == nil {
+		j.done = make(chan struct{})
+	}
+	select {
+	case <-j.done:
+	default:
+		close(j.done)
+		if j.stop != nil {
+			j.stop()
+		}
+	}
 }
 
+// NewJanitor call del every ci of the system clock
 func NewJanitor(ci time.Duration, del func()) *Janitor {
-	j := &Janitor{
+	return NewClockJanitor(basic.SystemClock, ci, del)
+}
+
+// NewClockJanitor call del every ci of clock
+func NewClockJanitor(clock basic.Clock, ci time.Duration, del func()) *Janitor {
+	return &Janitor{
 		Interval: ci,
-		stop:     make(chan bool),
+		clock:    clock,
+		stop:     clock.Every(ci, del),
 	}
-	go j.Run(del)
-	return j
 }
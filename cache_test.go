@@ -2,7 +2,10 @@ package stablecache
 
 import (
 	"bytes"
+	"stablecache/basic"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -23,6 +26,7 @@ func getmessage(key string) (m []byte, err error) {
 func TestCache(t *testing.T) {
 	Convey("normal cache", t, func() {
 		cache := New[string, []byte]("normal")
+		defer cache.Close()
 		cache.WithCallback(getmessage)
 		key := "123"
 		v, _ := getmessage(key)
@@ -31,3 +35,103 @@ func TestCache(t *testing.T) {
 		So(value, ShouldResemble, v)
 	})
 }
+
+func TestClock(t *testing.T) {
+	Convey("a cache on a fake clock expires without sleeping", t, func() {
+		clock := basic.NewFakeClock(time.Now())
+		cache := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer cache.Close()
+		cache.SetWithExp("a", 1, 10*time.Second)
+		clock.Advance(10 * time.Second)
+		v, err := cache.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		clock.Advance(1)
+		_, err = cache.Get("a")
+		So(err, ShouldEqual, Timeout)
+		So(cache.Len(), ShouldEqual, 0)
+		clock.Advance(time.Second)
+		_, err = cache.Peek("a")
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("the janitor reclaims a large invalidated cache in a few ticks", t, func() {
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		cache := NewLRUCache[int, int](40000, basic.WithClock(clock), basic.WithShards(4))
+		defer cache.Close()
+		for i := 0; i < 20000; i++ {
+			cache.Set(i, i)
+		}
+		cache.InvalidateAll()
+		for i := 0; i < 8; i++ {
+			clock.Advance(time.Second)
+		}
+		n := 0
+		t := cache.table.Load()
+		for i := range t.buckets {
+			n += len(t.buckets[i].items)
+		}
+		So(n, ShouldEqual, 0)
+		So(cache.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestClockRestore(t *testing.T) {
+	Convey("a cache on a fake clock restores its own snapshot", t, func() {
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		cache := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer cache.Close()
+		cache.SetWithExp("a", 1, time.Hour)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer restored.Close()
+		So(restored.Restore(&buf), ShouldBeNil)
+		So(restored.Len(), ShouldEqual, 1)
+		e, err := restored.GetEntry("a")
+		So(err, ShouldBeNil)
+		So(e.Stale, ShouldBeFalse)
+	})
+
+	Convey("a cache on a fake clock replays its own WAL", t, func() {
+		dir := t.TempDir()
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		w, _ := basic.OpenWAL[string, int](dir, nil)
+		cache := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer cache.Close()
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.SetWithExp("a", 1, time.Hour)
+		So(w.Close(), ShouldBeNil)
+
+		w, _ = basic.OpenWAL[string, int](dir, nil)
+		defer w.Close()
+		restored := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer restored.Close()
+		So(restored.WithWAL(w), ShouldBeNil)
+		So(restored.Len(), ShouldEqual, 1)
+	})
+}
+
+func TestJanitorRun(t *testing.T) {
+	Convey("Run calls del every interval until Stop", t, func() {
+		j := &Janitor{Interval: time.Millisecond}
+		var n atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			j.Run(func() { n.Add(1) })
+			close(done)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for n.Load() < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		So(n.Load(), ShouldBeGreaterThanOrEqualTo, 2)
+		j.Stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			So("Run did not return", ShouldBeEmpty)
+		}
+		j.Stop()
+	})
+}
@@ -0,0 +1,169 @@
+// Command stablecache-sim replays an access trace against every eviction
+// policy of stablecache and prints hit ratio, byte hit ratio and throughput
+// per policy and capacity.
+//
+//	stablecache-sim -trace P8.lis -format arc -capacity 1000,10000
+//	stablecache-sim -workload scan -requests 1000000 -keys 100000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ttl keeps every entry alive for the whole replay, only eviction counts
+const ttl = 24 * time.Hour
+
+type result struct {
+	policy     string
+	capacity   int
+	requests   int
+	hits       int
+	bytes      int64
+	hitBytes   int64
+	throughput float64
+}
+
+// replay run reqs against c, a miss is followed by a Set like a loader would
+func replay(c cache, reqs []request) result {
+	var r result
+	value := []byte{}
+	start := time.Now()
+	for _, req := range reqs {
+		r.requests++
+		r.bytes += req.size
+		if _, err := c.Get(req.key); err == nil {
+			r.hits++
+			r.hitBytes += req.size
+			continue
+		}
+		c.SetWithExp(req.key, value, ttl)
+	}
+	if d := time.Since(start); d > 0 {
+		r.throughput = float64(r.requests) / d.Seconds()
+	}
+	return r
+}
+
+func (r result) hitRatio() float64 {
+	if r.requests == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.requests)
+}
+
+func (r result) byteHitRatio() float64 {
+	if r.bytes == 0 {
+		return 0
+	}
+	return float64(r.hitBytes) / float64(r.bytes)
+}
+
+func parseInts(s string) ([]int, error) {
+	var ns []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad capacity %q", f)
+		}
+		ns = append(ns, n)
+	}
+	return ns, nil
+}
+
+func selectPolicies(s string) ([]policy, error) {
+	if s == "all" {
+		return policies, nil
+	}
+	var ps []policy
+	for _, name := range strings.Split(s, ",") {
+		found := false
+		for _, p := range policies {
+			if p.name == strings.TrimSpace(name) {
+				ps = append(ps, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown policy %q", name)
+		}
+	}
+	return ps, nil
+}
+
+func report(w io.Writer, results []result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "policy\tcapacity\trequests\thit ratio\tbyte hit ratio\tops/s\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.4f\t%.4f\t%.0f\t\n",
+			r.policy, r.capacity, r.requests, r.hitRatio(), r.byteHitRatio(), r.throughput)
+	}
+	tw.Flush()
+}
+
+func main() {
+	var (
+		trace    = flag.String("trace", "", "trace file, - for stdin; a synthetic workload is used when empty")
+		format   = flag.String("format", "keys", "trace format: keys, arc, lirs or csv (key,size)")
+		workload = flag.String("workload", "zipf", "synthetic workload: zipf, scan or loop")
+		requests = flag.Int("requests", 1000000, "synthetic workload length")
+		keys     = flag.Int("keys", 100000, "synthetic workload key space")
+		zipfS    = flag.Float64("zipf", 1.1, "zipf exponent, must be > 1")
+		seed     = flag.Int64("seed", 1, "synthetic workload seed")
+		capacity = flag.String("capacity", "1000,10000", "comma separated cache capacities")
+		only     = flag.String("policy", "all", "comma separated policies, or all")
+		shards   = flag.Int("shards", 1, "shards per cache; more shards split the capacity unevenly")
+	)
+	flag.Parse()
+	if err := run(os.Stdout, *trace, *format, *workload, *requests, *keys, *zipfS, *seed, *capacity, *only, *shards); err != nil {
+		fmt.Fprintln(os.Stderr, "stablecache-sim:", err)
+		os.Exit(1)
+	}
+}
+
+func run(w io.Writer, trace, format, workload string, requests, keys int, zipfS float64, seed int64,
+	capacity, only string, shards int) error {
+	caps, err := parseInts(capacity)
+	if err != nil {
+		return err
+	}
+	ps, err := selectPolicies(only)
+	if err != nil {
+		return err
+	}
+	var reqs []request
+	switch trace {
+	case "":
+		reqs, err = generate(workload, requests, keys, zipfS, seed)
+	case "-":
+		reqs, err = readTrace(os.Stdin, format)
+	default:
+		f, ferr := os.Open(trace)
+		if ferr != nil {
+			return ferr
+		}
+		reqs, err = readTrace(f, format)
+		f.Close()
+	}
+	if err != nil {
+		return err
+	}
+	var results []result
+	for _, c := range caps {
+		for _, p := range ps {
+			cache := p.new(c, shards)
+			r := replay(cache, reqs)
+			release(cache)
+			r.policy, r.capacity = p.name, c
+			results = append(results, r)
+		}
+	}
+	report(w, results)
+	return nil
+}
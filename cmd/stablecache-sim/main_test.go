@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplay(t *testing.T) {
+	Convey("byte hit ratio weighs hits by size", t, func() {
+		reqs := []request{{"a", 10}, {"b", 1}, {"a", 10}, {"b", 1}}
+		for _, p := range policies {
+			c := p.new(10, 1)
+			r := replay(c, reqs)
+			release(c)
+			So(r.hits, ShouldEqual, 2)
+			So(r.hitRatio(), ShouldEqual, 0.5)
+			So(r.byteHitRatio(), ShouldEqual, 0.5)
+		}
+	})
+
+	Convey("a loop larger than the cache never hits under lru", t, func() {
+		reqs, err := generate("loop", 1000, 100, 0, 1)
+		So(err, ShouldBeNil)
+		c := policies[0].new(50, 1)
+		defer release(c)
+		So(replay(c, reqs).hits, ShouldEqual, 0)
+	})
+
+	Convey("bad workload flags are errors, not panics", t, func() {
+		for _, c := range []struct {
+			kind    string
+			n, keys int
+			s       float64
+		}{
+			{"zipf", 100, 100, 1},
+			{"scan", 100, 100, 0.5},
+			{"zipf", 100, 1, 1.1},
+			{"loop", 100, 0, 0},
+			{"loop", -1, 100, 0},
+		} {
+			_, err := generate(c.kind, c.n, c.keys, c.s, 1)
+			So(err, ShouldNotBeNil)
+		}
+		var out bytes.Buffer
+		So(run(&out, "", "", "zipf", 100, 100, 1, 1, "10", "lru", 1), ShouldNotBeNil)
+	})
+
+	Convey("run prints a row per policy and capacity", t, func() {
+		var out bytes.Buffer
+		err := run(&out, "", "", "scan", 5000, 500, 1.1, 1, "50,100", "lru,s3-fifo", 1)
+		So(err, ShouldBeNil)
+		So(strings.Count(out.String(), "\n"), ShouldEqual, 5)
+		So(run(&out, "", "", "zipf", 10, 10, 1.1, 1, "10", "belady", 1), ShouldNotBeNil)
+	})
+}
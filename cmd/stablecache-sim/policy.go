@@ -0,0 +1,48 @@
+package main
+
+import (
+	"stablecache"
+	"stablecache/basic"
+	"time"
+)
+
+// cache is what the simulator needs from every policy
+type cache interface {
+	Get(string) ([]byte, error)
+	SetWithExp(string, []byte, time.Duration)
+}
+
+// release stop the janitor of c, if it has one
+func release(c cache) {
+	if c, ok := c.(interface{ Close() }); ok {
+		c.Close()
+	}
+}
+
+type policy struct {
+	name string
+	new  func(capacity, shards int) cache
+}
+
+func sharded(t stablecache.Type, opts ...basic.Option) func(int, int) cache {
+	return func(capacity, shards int) cache {
+		opts := append([]basic.Option{basic.WithShards(shards), basic.WithType(t)}, opts...)
+		return stablecache.NewLRUCache[string, []byte](uint64(capacity), opts...)
+	}
+}
+
+// policies is every eviction policy the library implements
+var policies = []policy{
+	{"lru", sharded(stablecache.LRU)},
+	{"lru+tinylfu", sharded(stablecache.LRU, basic.WithAdmission())},
+	{"w-tinylfu", sharded(stablecache.WTinyLFU)},
+	{"s3-fifo", sharded(stablecache.S3FIFO)},
+	{"2q", sharded(stablecache.TwoQ)},
+	{"slru", sharded(stablecache.SLRU)},
+	{"sieve", func(capacity, _ int) cache {
+		return basic.NewSieveCache[string, []byte](capacity)
+	}},
+	{"clock", func(capacity, shards int) cache {
+		return basic.NewPartitionCache[string, []byte](basic.WithCapacity(capacity), basic.WithShards(shards))
+	}},
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// request is one access of a trace
+type request struct {
+	key  string
+	size int64
+}
+
+// readTrace parse a trace in the given format:
+//   - keys: one key per line
+//   - arc: the ARC traces, "start blocks ignore id" per line, every line
+//     is the access of blocks consecutive blocks from start
+//   - lirs: the LIRS traces, one block number per line
+//   - csv: "key,size" per line, a header line is skipped
+func readTrace(r io.Reader, format string) ([]request, error) {
+	switch format {
+	case "keys":
+		return readLines(r, func(line string) ([]request, error) {
+			return []request{{key: line, size: 1}}, nil
+		})
+	case "arc":
+		return readLines(r, parseARC)
+	case "lirs":
+		return readLines(r, parseLIRS)
+	case "csv":
+		return readCSV(r)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+}
+
+func readLines(r io.Reader, parse func(string) ([]request, error)) ([]request, error) {
+	var reqs []request
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rs, err := parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		reqs = append(reqs, rs...)
+	}
+	return reqs, s.Err()
+}
+
+func parseARC(line string) ([]request, error) {
+	f := strings.Fields(line)
+	if len(f) < 2 {
+		return nil, fmt.Errorf("want start and block count, got %q", line)
+	}
+	start, err := strconv.ParseInt(f[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := strconv.ParseInt(f[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]request, 0, blocks)
+	for i := int64(0); i < blocks; i++ {
+		reqs = append(reqs, request{key: strconv.FormatInt(start+i, 10), size: 1})
+	}
+	return reqs, nil
+}
+
+func parseLIRS(line string) ([]request, error) {
+	f := strings.Fields(line)
+	if _, err := strconv.ParseInt(f[0], 10, 64); err != nil {
+		// the LIRS traces mark phases with non numeric lines
+		return nil, nil
+	}
+	return []request{{key: f[0], size: 1}}, nil
+}
+
+func readCSV(r io.Reader) ([]request, error) {
+	var reqs []request
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	for n := 1; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return reqs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		size := int64(1)
+		if len(rec) > 1 {
+			size, err = strconv.ParseInt(strings.TrimSpace(rec[1]), 10, 64)
+			if err != nil {
+				if n == 1 {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+		}
+		reqs = append(reqs, request{key: strings.TrimSpace(rec[0]), size: size})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func keysOf(reqs []request) []string {
+	var ks []string
+	for _, r := range reqs {
+		ks = append(ks, r.key)
+	}
+	return ks
+}
+
+func TestReadTrace(t *testing.T) {
+	Convey("key per line", t, func() {
+		reqs, err := readTrace(strings.NewReader("a\n\n# comment\nb\na\n"), "keys")
+		So(err, ShouldBeNil)
+		So(keysOf(reqs), ShouldResemble, []string{"a", "b", "a"})
+	})
+
+	Convey("arc lines expand to consecutive blocks", t, func() {
+		reqs, err := readTrace(strings.NewReader("10 3 0 1\n7 1 0 2\n"), "arc")
+		So(err, ShouldBeNil)
+		So(keysOf(reqs), ShouldResemble, []string{"10", "11", "12", "7"})
+		_, err = readTrace(strings.NewReader("10\n"), "arc")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("lirs skips non numeric lines", t, func() {
+		reqs, err := readTrace(strings.NewReader("5\n*\n6\n"), "lirs")
+		So(err, ShouldBeNil)
+		So(keysOf(reqs), ShouldResemble, []string{"5", "6"})
+	})
+
+	Convey("csv carries sizes and may have a header", t, func() {
+		reqs, err := readTrace(strings.NewReader("key,size\na,100\nb,20\n"), "csv")
+		So(err, ShouldBeNil)
+		So(reqs, ShouldResemble, []request{{"a", 100}, {"b", 20}})
+		_, err = readTrace(strings.NewReader("a,1\nb,x\n"), "csv")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("unknown format", t, func() {
+		_, err := readTrace(strings.NewReader(""), "xml")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+)
+
+// generate build a synthetic trace of n requests over a universe of keys
+//   - zipf: popularity follows a Zipf law with exponent s > 1
+//   - scan: Zipf traffic with a one-off scan of as many new keys every n/10
+//   - loop: the keys are requested round robin, the worst case of LRU
+func generate(kind string, n, keys int, s float64, seed int64) ([]request, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("requests must not be negative, got %d", n)
+	}
+	if keys <= 1 {
+		return nil, fmt.Errorf("keys must be more than 1, got %d", keys)
+	}
+	if (kind == "zipf" || kind == "scan") && !(s > 1) {
+		return nil, fmt.Errorf("zipf exponent must be more than 1, got %v", s)
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	reqs := make([]request, 0, n)
+	key := func(i uint64) request {
+		return request{key: strconv.FormatUint(i, 10), size: 1}
+	}
+	switch kind {
+	case "zipf":
+		z := rand.NewZipf(rnd, s, 1, uint64(keys-1))
+		for i := 0; i < n; i++ {
+			reqs = append(reqs, key(z.Uint64()))
+		}
+	case "scan":
+		z := rand.NewZipf(rnd, s, 1, uint64(keys-1))
+		next := uint64(keys)
+		for len(reqs) < n {
+			for i := 0; i < n/10 && len(reqs) < n; i++ {
+				reqs = append(reqs, key(z.Uint64()))
+			}
+			for i := 0; i < keys && len(reqs) < n; i++ {
+				reqs = append(reqs, key(next))
+				next++
+			}
+		}
+	case "loop":
+		for i := 0; i < n; i++ {
+			reqs = append(reqs, key(uint64(i%keys)))
+		}
+	default:
+		return nil, fmt.Errorf("unknown workload %q", kind)
+	}
+	return reqs, nil
+}
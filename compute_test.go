@@ -0,0 +1,56 @@
+package stablecache
+
+import (
+	"sync"
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUCompute(t *testing.T) {
+	Convey("lru computes never lose an update", t, func() {
+		cache := NewLRUCache[string, int](1000, basic.WithShards(4))
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 500; i++ {
+					cache.Compute("n", func(old int, exists bool) (int, Action) {
+						return old + 1, Update
+					})
+				}
+			}()
+		}
+		cache.Resize(16)
+		wg.Wait()
+		v, err := cache.Get("n")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 4000)
+		_, ok := cache.Compute("n", func(old int, exists bool) (int, Action) {
+			return 0, Remove
+		})
+		So(ok, ShouldBeFalse)
+		_, err = cache.Get("n")
+		So(err, ShouldEqual, NotFound)
+	})
+
+	Convey("lru set if absent, get or set and compare and swap", t, func() {
+		cache := NewLRUCache[string, int](1000)
+		So(cache.SetIfAbsent("a", 1), ShouldBeTrue)
+		So(cache.SetIfAbsent("a", 2), ShouldBeFalse)
+		v, loaded := cache.GetOrSet("a", 3)
+		So(loaded, ShouldBeTrue)
+		So(v, ShouldEqual, 1)
+		So(CompareAndSwap(cache, "a", 2, 5), ShouldBeFalse)
+		So(CompareAndSwap(cache, "a", 1, 5), ShouldBeTrue)
+		_, version, err := cache.GetVersion("a")
+		So(err, ShouldBeNil)
+		So(cache.CompareAndSwapVersion("a", version, 6), ShouldBeTrue)
+		So(cache.CompareAndSwapVersion("a", version, 7), ShouldBeFalse)
+		v, _ = cache.Get("a")
+		So(v, ShouldEqual, 6)
+	})
+}
@@ -0,0 +1,256 @@
+// Package debug serves an http.Handler to inspect and manipulate the
+// caches of a process: list them, look up or delete a key, see the
+// hottest keys, flush or warm them. Every page is HTML, or JSON with
+// ?format=json or an Accept: application/json header.
+//
+//	r := debug.NewRegistry()
+//	debug.Register(r, "users", users, debug.StringKey)
+//	mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", r))
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"stablecache/basic"
+)
+
+// Inspector is what the handler needs from a cache, basic.PartitionCache
+// and stablecache.LRUCache have it
+type Inspector[K comparable, V any] interface {
+	Shards() int
+	Len() int
+	Capacity() int
+	All() iter.Seq2[K, V]
+	GetEntry(K) (basic.EntryInfo[K, V], error)
+	Delete(K)
+	InvalidateAll()
+	Warm(context.Context, []K, ...basic.WarmupOption) (basic.WarmupReport[K], error)
+}
+
+// Info describe a registered cache
+type Info struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Shards   int    `json:"shards"`
+	Len      int    `json:"len"`
+	Capacity int    `json:"capacity"`
+}
+
+// Entry is a looked up key, see basic.EntryInfo
+type Entry struct {
+	Key        string        `json:"key"`
+	Value      any           `json:"value"`
+	Expiration time.Time     `json:"expiration"`
+	Duration   time.Duration `json:"duration"`
+	Created    time.Time     `json:"created"`
+	Hits       uint64        `json:"hits"`
+	Stale      bool          `json:"stale"`
+}
+
+// HotKey is a key with its hit count
+type HotKey struct {
+	Key  string `json:"key"`
+	Hits uint64 `json:"hits"`
+}
+
+// WarmResult is the outcome of a warmup, see basic.WarmupReport
+type WarmResult struct {
+	Total  int               `json:"total"`
+	Done   int               `json:"done"`
+	Failed int               `json:"failed"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// StringKey is the key parser of string keys
+func StringKey(s string) (string, error) {
+	return s, nil
+}
+
+// IntKey is the key parser of int keys
+func IntKey(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+// cache is a registered cache with its keys and values turned into text
+type cache struct {
+	info   func() Info
+	lookup func(string) (Entry, error)
+	delete func(string) error
+	hot    func(int) []HotKey
+	load   func() *basic.HotKeyStats
+	flush  func()
+	warm   func(context.Context, []string) (WarmResult, error)
+}
+
+// Registry holds the caches served by its handler, it is an http.Handler
+type Registry struct {
+	mu      sync.RWMutex
+	caches  map[string]*cache
+	handler http.Handler
+}
+
+// NewRegistry new empty registry
+func NewRegistry() *Registry {
+	r := &Registry{caches: make(map[string]*cache)}
+	r.handler = r.routes()
+	return r
+}
+
+// Register add c to r as name, replacing the cache of that name, if any.
+// parse turns the keys of requests into K.
+func Register[K comparable, V any](r *Registry, name string, c Inspector[K, V], parse func(string) (K, error)) {
+	typ := fmt.Sprintf("%T", c)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caches[name] = &cache{
+		info: func() Info {
+			return Info{Name: name, Type: typ, Shards: c.Shards(), Len: c.Len(), Capacity: c.Capacity()}
+		},
+		lookup: func(s string) (Entry, error) {
+			k, err := parse(s)
+			if err != nil {
+				return Entry{}, err
+			}
+			e, err := c.GetEntry(k)
+			if err != nil {
+				return Entry{}, err
+			}
+			return Entry{
+				Key:        s,
+				Value:      e.Value,
+				Expiration: e.Expiration,
+				Duration:   e.Duration,
+				Created:    e.Created,
+				Hits:       e.Hits,
+				Stale:      e.Stale,
+			}, nil
+		},
+		delete: func(s string) error {
+			k, err := parse(s)
+			if err != nil {
+				return err
+			}
+			c.Delete(k)
+			return nil
+		},
+		hot: func(n int) []HotKey {
+			return hotKeys(c, n)
+		},
+		load: func() *basic.HotKeyStats {
+			if t, ok := c.(tracker[K]); ok {
+				if s := t.HotKeyStats(); s.Sample > 0 {
+					return &s
+				}
+			}
+			return nil
+		},
+		flush: c.InvalidateAll,
+		warm: func(ctx context.Context, ss []string) (WarmResult, error) {
+			ks := make([]K, 0, len(ss))
+			for _, s := range ss {
+				k, err := parse(s)
+				if err != nil {
+					return WarmResult{}, fmt.Errorf("key %q: %w", s, err)
+				}
+				ks = append(ks, k)
+			}
+			report, err := c.Warm(ctx, ks)
+			res := WarmResult{Total: report.Total, Done: report.Done, Failed: report.Failed}
+			for k, e := range report.Errors {
+				if res.Errors == nil {
+					res.Errors = make(map[string]string)
+				}
+				res.Errors[fmt.Sprint(k)] = e.Error()
+			}
+			return res, err
+		},
+	}
+}
+
+// tracker is a cache built with basic.WithHotKeys
+type tracker[K comparable] interface {
+	HotKeys(int) []basic.HotKey[K]
+	HotKeyStats() basic.HotKeyStats
+}
+
+// Unregister remove the cache called name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	delete(r.caches, name)
+	r.mu.Unlock()
+}
+
+func (r *Registry) get(name string) (*cache, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.caches[name]
+	return c, ok
+}
+
+// infos describe every cache, sorted by name
+func (r *Registry) infos() []Info {
+	r.mu.RLock()
+	caches := make([]*cache, 0, len(r.caches))
+	for _, c := range r.caches {
+		caches = append(caches, c)
+	}
+	r.mu.RUnlock()
+	infos := make([]Info, 0, len(caches))
+	for _, c := range caches {
+		infos = append(infos, c.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// hotKeys return the n keys with the most hits. A cache tracking hot keys
+// answers from its tracker, with the hits estimated from the samples;
+// otherwise the whole cache is walked.
+func hotKeys[K comparable, V any](c Inspector[K, V], n int) []HotKey {
+	var hot []HotKey
+	if t, ok := c.(tracker[K]); ok {
+		if s := t.HotKeyStats(); s.Sample > 0 {
+			for _, k := range t.HotKeys(n) {
+				hot = append(hot, HotKey{Key: fmt.Sprint(k.Key), Hits: k.Count * uint64(s.Sample)})
+			}
+			return hot
+		}
+	}
+	for k := range c.All() {
+		e, err := c.GetEntry(k)
+		if err != nil || e.Hits == 0 {
+			continue
+		}
+		hot = append(hot, HotKey{Key: fmt.Sprint(k), Hits: e.Hits})
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Hits != hot[j].Hits {
+			return hot[i].Hits > hot[j].Hits
+		}
+		return hot[i].Key < hot[j].Key
+	})
+	if len(hot) > n {
+		hot = hot[:n]
+	}
+	return hot
+}
+
+// jsonValue keep v when it encodes to JSON, its text otherwise
+func jsonValue(v any) any {
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprint(v)
+	}
+	return v
+}
+
+// errNoCache is returned for a cache name nobody registered
+var errNoCache = errors.New("no such cache")
@@ -0,0 +1,138 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"stablecache"
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandler(t *testing.T) {
+	Convey("the handler inspects and manipulates registered caches", t, func() {
+		users := basic.NewPartitionCache[string, int](basic.WithShards(4))
+		defer users.Close()
+		users.WithCallback(func(k string) (int, error) {
+			if k == "bad" {
+				return 0, errors.New("bad key")
+			}
+			return len(k), nil
+		})
+		ids := stablecache.NewLRUCache[int, string](100)
+		r := NewRegistry()
+		Register[string, int](r, "users", users, StringKey)
+		Register[int, string](r, "ids", ids, IntKey)
+		mux := http.NewServeMux()
+		mux.Handle("/debug/cache/", http.StripPrefix("/debug/cache", r))
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+		base := srv.URL + "/debug/cache/"
+
+		getJSON := func(path string, code int, v any) {
+			resp, err := http.Get(base + path)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, code)
+			So(json.NewDecoder(resp.Body).Decode(v), ShouldBeNil)
+		}
+		post := func(path string, form url.Values) *http.Response {
+			resp, err := http.PostForm(base+path, form)
+			So(err, ShouldBeNil)
+			return resp
+		}
+
+		for i := 0; i < 3; i++ {
+			users.Set("alice", 1)
+		}
+		users.Get("alice")
+		users.Get("alice")
+		users.Set("bob", 2)
+		users.Get("bob")
+		ids.Set(7, "seven")
+
+		var infos []Info
+		getJSON("?format=json", http.StatusOK, &infos)
+		So(len(infos), ShouldEqual, 2)
+		So(infos[0].Name, ShouldEqual, "ids")
+		So(infos[0].Capacity, ShouldEqual, 100)
+		So(infos[0].Type, ShouldContainSubstring, "LRUCache")
+		So(infos[1].Shards, ShouldEqual, 4)
+		So(infos[1].Len, ShouldEqual, 2)
+
+		var page struct {
+			Info
+			Hot []HotKey `json:"hot"`
+		}
+		getJSON("users?format=json&n=1", http.StatusOK, &page)
+		So(page.Hot, ShouldResemble, []HotKey{{Key: "alice", Hits: 2}})
+
+		var e Entry
+		getJSON("ids/key?format=json&k=7", http.StatusOK, &e)
+		So(e.Value, ShouldEqual, "seven")
+		var fail struct{ Error string }
+		getJSON("ids/key?format=json&k=8", http.StatusNotFound, &fail)
+		getJSON("ids/key?format=json&k=x", http.StatusBadRequest, &fail)
+		getJSON("nope?format=json", http.StatusNotFound, &fail)
+		So(fail.Error, ShouldEqual, "no such cache")
+
+		// html posts go back to the cache page
+		resp := post("users/delete", url.Values{"k": {"bob"}})
+		resp.Body.Close()
+		So(resp.Request.URL.Path, ShouldEqual, "/debug/cache/users")
+		_, err := users.Peek("bob")
+		So(err, ShouldEqual, basic.NotFound)
+
+		resp = post("users/warm?format=json", url.Values{"k": {"carol\ndave", "bad"}})
+		var warm WarmResult
+		So(json.NewDecoder(resp.Body).Decode(&warm), ShouldBeNil)
+		resp.Body.Close()
+		So(warm.Total, ShouldEqual, 3)
+		So(warm.Failed, ShouldEqual, 1)
+		So(warm.Errors["bad"], ShouldEqual, "bad key")
+		v, _ := users.Peek("carol")
+		So(v, ShouldEqual, 5)
+
+		resp = post("ids/warm?format=json", url.Values{"k": {"1"}})
+		resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusConflict)
+
+		resp = post("users/flush", nil)
+		resp.Body.Close()
+		So(users.Len(), ShouldEqual, 0)
+
+		resp, err = http.Get(base + "users")
+		So(err, ShouldBeNil)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(resp.Header.Get("Content-Type"), ShouldStartWith, "text/html")
+		So(strings.Contains(string(body), `action="users/flush"`), ShouldBeTrue)
+
+		tracked := basic.NewPartitionCache[string, int](basic.WithShards(2), basic.WithHotKeys(4, 1))
+		defer tracked.Close()
+		tracked.Set("x", 1)
+		tracked.Get("x")
+		tracked.Get("x")
+		Register[string, int](r, "tracked", tracked, StringKey)
+		var load struct {
+			Hot  []HotKey          `json:"hot"`
+			Load basic.HotKeyStats `json:"load"`
+		}
+		getJSON("tracked?format=json", http.StatusOK, &load)
+		So(load.Hot, ShouldResemble, []HotKey{{Key: "x", Hits: 2}})
+		So(load.Load.Sampled, ShouldEqual, 2)
+		So(load.Load.Imbalance, ShouldEqual, 2)
+		r.Unregister("tracked")
+
+		r.Unregister("ids")
+		getJSON("?format=json", http.StatusOK, &infos)
+		So(len(infos), ShouldEqual, 1)
+	})
+}
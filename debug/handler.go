@@ -0,0 +1,187 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"stablecache"
+	"stablecache/basic"
+)
+
+// ServeHTTP serve the pages of the registry:
+//
+//	GET  /              the registered caches
+//	GET  /{name}        one cache and its top ?n= (10) hottest keys
+//	GET  /{name}/key    the entry of ?k=
+//	POST /{name}/delete delete the key k
+//	POST /{name}/flush  invalidate every entry
+//	POST /{name}/warm   load the keys k, repeated or one per line
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func (r *Registry) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, req *http.Request) {
+		render(w, req, "index", r.infos())
+	})
+	mux.HandleFunc("GET /{name}", r.with(func(w http.ResponseWriter, req *http.Request, c *cache) {
+		n, err := strconv.Atoi(req.FormValue("n"))
+		if err != nil || n <= 0 {
+			n = 10
+		}
+		render(w, req, "cache", struct {
+			Info
+			Hot  []HotKey           `json:"hot"`
+			Load *basic.HotKeyStats `json:"load,omitempty"`
+		}{c.info(), c.hot(n), c.load()})
+	}))
+	mux.HandleFunc("GET /{name}/key", r.with(func(w http.ResponseWriter, req *http.Request, c *cache) {
+		e, err := c.lookup(req.FormValue("k"))
+		if err != nil {
+			fail(w, req, err)
+			return
+		}
+		e.Value = jsonValue(e.Value)
+		render(w, req, "entry", struct {
+			Cache string `json:"cache"`
+			Entry
+		}{req.PathValue("name"), e})
+	}))
+	mux.HandleFunc("POST /{name}/delete", r.with(func(w http.ResponseWriter, req *http.Request, c *cache) {
+		if err := c.delete(req.FormValue("k")); err != nil {
+			fail(w, req, err)
+			return
+		}
+		done(w, req)
+	}))
+	mux.HandleFunc("POST /{name}/flush", r.with(func(w http.ResponseWriter, req *http.Request, c *cache) {
+		c.flush()
+		done(w, req)
+	}))
+	mux.HandleFunc("POST /{name}/warm", r.with(func(w http.ResponseWriter, req *http.Request, c *cache) {
+		req.ParseForm()
+		var ks []string
+		for _, v := range req.Form["k"] {
+			for _, k := range strings.Split(v, "\n") {
+				if k = strings.TrimSpace(k); k != "" {
+					ks = append(ks, k)
+				}
+			}
+		}
+		res, err := c.warm(req.Context(), ks)
+		if err != nil {
+			fail(w, req, err)
+			return
+		}
+		render(w, req, "warm", struct {
+			Cache string `json:"cache"`
+			WarmResult
+		}{req.PathValue("name"), res})
+	}))
+	return mux
+}
+
+// with look up the cache named in the path for h
+func (r *Registry) with(h func(http.ResponseWriter, *http.Request, *cache)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		c, ok := r.get(req.PathValue("name"))
+		if !ok {
+			fail(w, req, errNoCache)
+			return
+		}
+		h(w, req, c)
+	}
+}
+
+func wantJSON(req *http.Request) bool {
+	return req.FormValue("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+func render(w http.ResponseWriter, req *http.Request, page string, data any) {
+	if wantJSON(req) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	pages.ExecuteTemplate(w, page, data)
+}
+
+// done answer a POST: ok in JSON, back to the cache page in HTML
+func done(w http.ResponseWriter, req *http.Request) {
+	if wantJSON(req) {
+		render(w, req, "", struct {
+			OK bool `json:"ok"`
+		}{true})
+		return
+	}
+	// relative to the URL of the client: the path of req may be stripped
+	w.Header().Set("Location", "../"+url.PathEscape(req.PathValue("name")))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+func fail(w http.ResponseWriter, req *http.Request, err error) {
+	code := http.StatusBadRequest
+	switch {
+	case errors.Is(err, errNoCache), errors.Is(err, basic.NotFound), errors.Is(err, stablecache.NotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, basic.NoCallback):
+		code = http.StatusConflict
+	}
+	if wantJSON(req) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		return
+	}
+	http.Error(w, err.Error(), code)
+}
+
+var pages = template.Must(template.New("").Parse(`
+{{define "head"}}<!DOCTYPE html><html><head><title>stablecache</title>
+<style>body{font-family:monospace}td,th{padding:2px 12px;text-align:left}</style></head><body>{{end}}
+
+{{define "index"}}{{template "head"}}
+<h1>caches</h1>
+<table><tr><th>name</th><th>type</th><th>shards</th><th>len</th><th>capacity</th></tr>
+{{range .}}<tr><td><a href="{{.Name}}">{{.Name}}</a></td><td>{{.Type}}</td><td>{{.Shards}}</td><td>{{.Len}}</td><td>{{.Capacity}}</td></tr>
+{{end}}</table></body></html>{{end}}
+
+{{define "cache"}}{{template "head"}}
+<h1>{{.Name}}</h1>
+<p>{{.Type}}, {{.Shards}} shards, {{.Len}} entries, capacity {{.Capacity}}</p>
+<form action="{{.Name}}/key"><input name="k" placeholder="key"> <button>look up</button></form>
+<form action="{{.Name}}/delete" method="post"><input name="k" placeholder="key"> <button>delete</button></form>
+<form action="{{.Name}}/warm" method="post"><textarea name="k" placeholder="one key per line"></textarea> <button>warm</button></form>
+<form action="{{.Name}}/flush" method="post"><button>flush</button></form>
+{{with .Load}}<p>shard load: {{printf "%.2f" .Imbalance}} times the mean on shard {{.Hottest}}, {{.Shards}}</p>{{end}}
+<h2>hot keys</h2>
+<table><tr><th>key</th><th>hits</th></tr>
+{{$name := .Name}}{{range .Hot}}<tr><td><a href="{{$name}}/key?k={{.Key}}">{{.Key}}</a></td><td>{{.Hits}}</td></tr>
+{{end}}</table></body></html>{{end}}
+
+{{define "entry"}}{{template "head"}}
+<h1><a href="../{{.Cache}}">{{.Cache}}</a> / {{.Key}}</h1>
+<table>
+<tr><th>value</th><td>{{printf "%v" .Value}}</td></tr>
+<tr><th>expiration</th><td>{{.Expiration}}</td></tr>
+<tr><th>duration</th><td>{{.Duration}}</td></tr>
+<tr><th>created</th><td>{{.Created}}</td></tr>
+<tr><th>hits</th><td>{{.Hits}}</td></tr>
+<tr><th>stale</th><td>{{.Stale}}</td></tr>
+</table></body></html>{{end}}
+
+{{define "warm"}}{{template "head"}}
+<h1><a href="../{{.Cache}}">{{.Cache}}</a> warmup</h1>
+<p>{{.Done}} of {{.Total}} done, {{.Failed}} failed</p>
+<table>{{range $k, $e := .Errors}}<tr><td>{{$k}}</td><td>{{$e}}</td></tr>{{end}}</table>
+</body></html>{{end}}
+`))
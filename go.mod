@@ -1,6 +1,6 @@
 module stablecache
 
-go 1.19
+go 1.23
 
 replace basic => ./basic
 
@@ -0,0 +1,21 @@
+package stablecache
+
+import "stablecache/basic"
+
+// HotKeys return the n hottest keys of the Gets of the cache itself,
+// namespaces not included. It is nil unless basic.WithHotKeys is given.
+func (c *LRUCache[K, V]) HotKeys(n int) []basic.HotKey[K] {
+	return c.hot.Top(n)
+}
+
+// HotKeyStats return the load of every shard as seen by the hot key
+// tracker, against the current ehash to shard mapping
+func (c *LRUCache[K, V]) HotKeyStats() basic.HotKeyStats {
+	return c.hot.Stats()
+}
+
+// Replicated return the keys served from a lock-free copy, see
+// basic.WithHotKeyReplicas
+func (c *LRUCache[K, V]) Replicated() []K {
+	return c.replicas.Keys()
+}
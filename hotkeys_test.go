@@ -0,0 +1,64 @@
+package stablecache
+
+import (
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHotKeys(t *testing.T) {
+	Convey("the lru records its Gets", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(2), basic.WithHotKeys(2, 1))
+		for i := 0; i < 5; i++ {
+			cache.Set(i, i)
+		}
+		for i := 0; i < 20; i++ {
+			cache.Get(3)
+			cache.Get(i % 5)
+		}
+		top := cache.HotKeys(1)
+		So(top[0].Key, ShouldEqual, 3)
+		So(top[0].Count, ShouldBeGreaterThanOrEqualTo, 24)
+		s := cache.HotKeyStats()
+		So(s.Sampled, ShouldEqual, 40)
+		So(s.Imbalance, ShouldBeBetweenOrEqual, 1, 2)
+	})
+	Convey("replicated keys stay coherent with the shards", t, func() {
+		cache := NewLRUCache[string, int](100, basic.WithShards(4), basic.WithHotKeyReplicas(2), basic.WithHotKeys(8, 2))
+		cache.Set("hot", 1)
+		for i := 0; i < 200; i++ {
+			cache.Get("hot")
+		}
+		cache.deleteExpired()
+		So(cache.Replicated(), ShouldResemble, []string{"hot"})
+		read := func(want int, err error) {
+			for i := 0; i < 50; i++ {
+				v, e := cache.Get("hot")
+				So(e, ShouldEqual, err)
+				So(v, ShouldEqual, want)
+			}
+		}
+		read(1, nil)
+		_, ok := cache.replicas.Load("hot")
+		So(ok, ShouldBeTrue)
+		cache.Set("hot", 2)
+		read(2, nil)
+		So(cache.Touch("hot", time.Minute), ShouldBeTrue)
+		read(2, nil)
+		e, _ := cache.GetEntry("hot")
+		So(e.Duration, ShouldEqual, time.Minute)
+		cache.Resize(2)
+		read(2, nil)
+		e, _ = cache.GetEntry("hot")
+		So(e.Hits, ShouldEqual, 400)
+		cache.Delete("hot")
+		read(0, NotFound)
+		cache.Set("hot", 3)
+		read(3, nil)
+		cache.InvalidateAll()
+		read(0, NotFound)
+	})
+}
@@ -0,0 +1,81 @@
+package stablecache
+
+import (
+	"iter"
+	"stablecache/basic"
+)
+
+// All iterate over the live entries, expired ones are skipped. Shards are
+// read-locked one at a time, each in eviction order; entries set meanwhile
+// may or may not show up.
+func (c *LRUCache[K, V]) All() iter.Seq2[K, V] {
+	t := c.table.Load()
+	return basic.ShardSeq(len(t.buckets), func(i int, dst []basic.Entry[K, V]) []basic.Entry[K, V] {
+		now := c.stamps.clock.Now()
+		t.walk(i, nil, func(_ uintptr, item LRUItem[K, V], epoch uint64) {
+			if item.ns == nil && !item.stale(epoch) && !item.expiredAt(now) {
+				dst = append(dst, basic.Entry[K, V]{Key: item.key, Value: item.obj})
+			}
+		})
+		return dst
+	})
+}
+
+// Range call fn for every live entry until it returns false, see All
+func (c *LRUCache[K, V]) Range(fn func(K, V) bool) {
+	c.All()(fn)
+}
+
+// Keys return the keys of the live entries, see All
+func (c *LRUCache[K, V]) Keys() []K {
+	return basic.Keys(c.All())
+}
+
+// Len return the number of live entries, namespaces not included. It
+// walks the shards like All.
+func (c *LRUCache[K, V]) Len() int {
+	t := c.table.Load()
+	now := c.stamps.clock.Now()
+	n := 0
+	for i := range t.buckets {
+		t.walk(i, nil, func(_ uintptr, item LRUItem[K, V], epoch uint64) {
+			if item.ns == nil && !item.stale(epoch) && !item.expiredAt(now) {
+				n++
+			}
+		})
+	}
+	return n
+}
+
+// walk call fn for the entries of shard i that keep accepts, under its
+// read lock, see basic.PartitionCache
+func (t *lruTable[K, V]) walk(i int, keep func(uintptr) bool, fn func(uintptr, LRUItem[K, V], uint64)) {
+	b := &t.buckets[i]
+	b.mu.RLock()
+	next := b.moved
+	if next == nil {
+		epoch := b.stamps.epoch.Load()
+		b.policy.each(func(h uintptr) {
+			if keep == nil || keep(h) {
+				fn(h, b.items[h], epoch)
+			}
+		})
+		b.mu.RUnlock()
+		return
+	}
+	b.mu.RUnlock()
+	from := func(h uintptr) bool {
+		return h&t.mask == uintptr(i) && (keep == nil || keep(h))
+	}
+	m := t.mask & next.mask
+	for j := range next.buckets {
+		if uintptr(j)&m == uintptr(i)&m {
+			next.walk(j, from, fn)
+		}
+	}
+}
+
+// expiredAt is Expired at now
+func (i *LRUItem[K, V]) expiredAt(now int64) bool {
+	return i.expiration != 0 && now > i.expiration
+}
@@ -0,0 +1,41 @@
+package stablecache
+
+import (
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUIter(t *testing.T) {
+	Convey("lru walks live entries in eviction order per shard", t, func() {
+		cache := NewLRUCache[int, int](1000, basic.WithShards(1))
+		for i := 0; i < 10; i++ {
+			cache.Set(i, i)
+		}
+		cache.SetWithExp(100, 1, time.Nanosecond)
+		cache.Namespace("n").Set(200, 1)
+		time.Sleep(time.Millisecond)
+		So(cache.Len(), ShouldEqual, 10)
+		So(cache.Keys(), ShouldResemble, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+		n := 0
+		for k, v := range cache.All() {
+			So(v, ShouldEqual, k)
+			n++
+			if n == 3 {
+				break
+			}
+		}
+		So(n, ShouldEqual, 3)
+		cache.Resize(8)
+		So(cache.Len(), ShouldEqual, 10)
+		sum := 0
+		cache.Range(func(k, v int) bool {
+			sum += v
+			return true
+		})
+		So(sum, ShouldEqual, 45)
+	})
+}
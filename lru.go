@@ -1,11 +1,12 @@
 package stablecache
 
 import (
-	"container/list"
 	"fmt"
+	"math/rand/v2"
 	"runtime"
 	"stablecache/basic"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 )
@@ -15,10 +16,17 @@ type LRUItem[K comparable, V any] struct {
 	key        K
 	expiration int64
 	duration   int64
-	p          *list.Element
+	p          *node
+	tags       []string
+	ns         *Namespace[K, V]
+	gen        uint64
+	epoch      uint64
+	version    uint64
+	created    int64
 }
 
-// Expired is expired data
+// Expired is expired data by the system clock, a cache checks its entries
+// against the clock of its options
 func (i *LRUItem[K, V]) Expired() bool {
 	if i.expiration == 0 {
 		return false
@@ -32,161 +40,416 @@ type LRUBucket[K comparable, V any] struct {
 	defaultDuration time.Duration
 	mu              sync.RWMutex
 	items           map[uintptr]LRUItem[K, V]
-	order           *list.List
+	policy          policy
 	size            uint64
+	moved           *lruTable[K, V]
+	reads           [readStripes]readBuffer
+	idx             *basic.KeyIndex[K]
+	stamps          *stamps
+	replicas        *basic.HotReplicas[K, LRUItem[K, V]]
 }
 
 func (b *LRUBucket[K, V]) clean() {
-	b.order = nil
+	b.policy = nil
 	b.items = nil
 }
 
-func (b *LRUBucket[K, V]) initBucket(size uint64) {
+func (b *LRUBucket[K, V]) initBucket(size uint64, o basic.Options, idx *basic.KeyIndex[K], stamps *stamps, replicas *basic.HotReplicas[K, LRUItem[K, V]]) {
 	b.items = make(map[uintptr]LRUItem[K, V])
-	b.order = list.New()
+	b.policy = newPolicy(o, size)
 	b.size = size
+	b.idx = idx
+	b.stamps = stamps
+	b.replicas = replicas
 }
 
-// Get LRUBucket value
+// rlock read-lock the bucket owning h, following the forwarding pointer
+// left behind by Resize
+func (b *LRUBucket[K, V]) rlock(h uintptr) *LRUBucket[K, V] {
+	for {
+		b.mu.RLock()
+		if b.moved == nil {
+			return b
+		}
+		next := b.moved.bucket(h)
+		b.mu.RUnlock()
+		b = next
+	}
+}
+
+// lock write-lock the bucket owning h, following the forwarding pointer
+// left behind by Resize
+func (b *LRUBucket[K, V]) lock(h uintptr) *LRUBucket[K, V] {
+	for {
+		b.mu.Lock()
+		if b.moved == nil {
+			return b
+		}
+		next := b.moved.bucket(h)
+		b.mu.Unlock()
+		b = next
+	}
+}
+
+// migrate move every entry into next and leave a forwarding pointer behind.
+// The entries are handed over from the first to be evicted to the last, so
+// every new bucket keeps their relative order.
+func (b *LRUBucket[K, V]) migrate(next *lruTable[K, V]) {
+	b.mu.Lock()
+	b.drain()
+	b.policy.each(func(h uintptr) {
+		t := next.bucket(h)
+		t.mu.Lock()
+		t.insert(h, b.items[h])
+		t.mu.Unlock()
+	})
+	b.clean()
+	b.moved = next
+	b.mu.Unlock()
+}
+
+// Get LRUBucket value of k in namespace ns, nil is the cache itself
 // error maybe not found, timeout
-func (b *LRUBucket[K, V]) Get(p *LRUCache[K, V], k K, h uintptr) (r V, err error) {
-	b.mu.RLock()
+func (b *LRUBucket[K, V]) Get(p *LRUCache[K, V], ns *Namespace[K, V], k K, h uintptr) (r V, err error) {
+	b = b.rlock(h)
 	item, ok := b.items[h]
-	if !ok || item.key != k {
-		if p.caller == nil {
+	ok = ok && item.owned(k, ns, b.stamps.epoch.Load())
+	// touch under the read lock: once it is released a Resize may take
+	// the policy away from b
+	touched := false
+	if ok {
+		if ns == nil {
+			b.replicas.Fill(k, item)
+		}
+		touched = b.policy.touch(item.p)
+	}
+	b.mu.RUnlock()
+	if !ok {
+		ns.miss()
+		caller := p.callerOf(ns)
+		if caller == nil {
 			return r, NotFound
 		}
-		b.mu.RUnlock()
-		v, err := p.caller(k)
+		v, err := caller(k)
+		ns.loaded(err)
 		if err != nil {
 			return r, NotFound
 		}
-		b.SetWithExp(k, v, h, p.defaultDuration)
+		b.put(h, p.item(ns, k, v, p.durationOf(ns), nil), p.wal.Load())
 		return v, nil
 	}
-	b.mu.RUnlock()
-	b.mu.Lock()
-	b.move(item.p)
-	b.mu.Unlock()
-	if item.Expired() {
-		b.refresh(p, k, h, item)
+	ns.hit()
+	item.p.hits.Add(1)
+	if !touched {
+		b.record(item.p)
+	}
+	if item.expiredAt(b.now()) {
+		b.refresh(p, ns, k, h, item)
 		return item.obj, Timeout
 	}
-	b.refresh(p, k, h, item)
+	b.refresh(p, ns, k, h, item)
 	return item.obj, nil
 }
 
 // SetWithExp actively set LRUBucket value
-func (b *LRUBucket[K, V]) SetWithExp(k K, v V, h uintptr, dur time.Duration) (*list.Element, bool) {
-	b.mu.Lock()
-	i, ok := b.items[h]
-	if ok {
-		i.key = k
-		i.obj = v
-		i.expiration = time.Now().Add(dur).UnixNano()
-		i.duration = int64(dur)
-		b.mu.Unlock()
-		return i.p, true
-	}
-	p := b.add(k)
-	b.items[h] = LRUItem[K, V]{
+func (b *LRUBucket[K, V]) SetWithExp(k K, v V, h uintptr, dur time.Duration, w *basic.WAL[K, V]) {
+	b.set(k, v, h, b.now()+int64(dur), int64(dur), nil, w)
+}
+
+// set store v with an absolute expiration and tags, and log it to w, if any
+func (b *LRUBucket[K, V]) set(k K, v V, h uintptr, expiration, duration int64, tags []string, w *basic.WAL[K, V]) {
+	b.put(h, LRUItem[K, V]{
 		key:        k,
 		obj:        v,
-		expiration: time.Now().Add(dur).UnixNano(),
-		duration:   int64(dur),
-		p:          p,
+		expiration: expiration,
+		duration:   duration,
+		tags:       tags,
+	}, w)
+}
+
+// put store item, replacing the entry of the same key, namespace and
+// generation, or whatever else sits on h. Entries of the cache itself are
+// logged to w, if any; a namespace over its quota drops new keys.
+func (b *LRUBucket[K, V]) put(h uintptr, item LRUItem[K, V], w *basic.WAL[K, V]) {
+	b = b.lock(h)
+	b.drain()
+	_, seq := b.store(h, item, w)
+	b.mu.Unlock()
+	w.Wait(seq)
+}
+
+// store is put under the write lock, it reports whether item was stored
+// and returns the sequence number of its record for Wait
+func (b *LRUBucket[K, V]) store(h uintptr, item LRUItem[K, V], w *basic.WAL[K, V]) (bool, uint64) {
+	item.epoch = b.stamps.epoch.Load()
+	item.version = b.stamps.version.Add(1)
+	i, ok := b.items[h]
+	if ok && i.key == item.key && i.ns == item.ns && i.gen == item.gen && i.epoch == item.epoch {
+		if i.ns == nil {
+			b.idx.Update(h, i.key, i.tags, item.tags)
+		}
+		i.obj = item.obj
+		i.expiration = item.expiration
+		i.duration = item.duration
+		i.tags = item.tags
+		i.version = item.version
+		b.items[h] = i
+		b.policy.hit(i.p)
+		if i.ns == nil {
+			b.replicas.Store(i.key, i)
+		}
+	} else {
+		if ok {
+			b.remove(h, i)
+		}
+		if !item.ns.admit() {
+			return false, 0
+		}
+		item.created = b.now()
+		if !b.insert(h, item) {
+			// rejected by admission, nothing to index or log
+			return false, 0
+		}
+		if item.ns == nil {
+			b.idx.Insert(h, item.key, item.tags)
+		}
 	}
+	if item.ns != nil {
+		return true, 0
+	}
+	return true, w.Append(basic.OpSet, &basic.Entry[K, V]{Key: item.key, Value: item.obj, Expiration: item.expiration, Duration: item.duration, Tags: item.tags})
+}
+
+// delete remove k of namespace ns when cond, if any, holds for it and
+// log it to w as op, if any. It reports whether k was removed.
+func (b *LRUBucket[K, V]) delete(k K, h uintptr, ns *Namespace[K, V], op basic.Op, cond func(LRUItem[K, V]) bool, w *basic.WAL[K, V]) bool {
+	b = b.lock(h)
+	b.drain()
+	i, ok := b.items[h]
+	if !ok || !i.owned(k, ns, b.stamps.epoch.Load()) || (cond != nil && !cond(i)) {
+		b.mu.Unlock()
+		return false
+	}
+	b.remove(h, i)
+	seq := w.Append(op, &basic.Entry[K, V]{Key: k})
 	b.mu.Unlock()
-	return p, true
+	w.Wait(seq)
+	return true
+}
+
+// remove drop the entry of h, the write lock must be held
+func (b *LRUBucket[K, V]) remove(h uintptr, item LRUItem[K, V]) {
+	b.policy.remove(item.p)
+	delete(b.items, h)
+	b.forget(h, item)
 }
 
-func (b *LRUBucket[K, V]) refresh(p *LRUCache[K, V], k K, h uintptr, tItem LRUItem[K, V]) {
-	if p.caller == nil {
+// insert add a new entry and evict while the bucket is over its size,
+// the write lock must be held. It reports false when the policy evicted
+// the new entry itself, as admission does for an entry colder than its
+// victim.
+func (b *LRUBucket[K, V]) insert(h uintptr, item LRUItem[K, V]) bool {
+	old := item.p
+	item.p = b.policy.add(h)
+	if old != nil {
+		item.p.hits.Store(old.hits.Load())
+	}
+	b.items[h] = item
+	if item.ns == nil {
+		b.replicas.Store(item.key, item)
+	}
+	for uint64(len(b.items)) > b.size {
+		victim, ok := b.policy.evict()
+		if !ok {
+			break
+		}
+		b.forget(victim, b.items[victim])
+		delete(b.items, victim)
+	}
+	_, ok := b.items[h]
+	return ok
+}
+
+// now is the time of the clock of the cache
+func (b *LRUBucket[K, V]) now() int64 {
+	return b.stamps.clock.Now()
+}
+
+// forget drop item from the key index, or from the count of its
+// namespace, the write lock must be held
+func (b *LRUBucket[K, V]) forget(h uintptr, item LRUItem[K, V]) {
+	if item.ns != nil {
+		item.ns.release(item.gen)
 		return
 	}
-	t := tItem.expiration - time.Now().UnixNano()
+	b.idx.Remove(h, item.key, item.tags)
+	b.replicas.Drop(item.key)
+}
+
+func (b *LRUBucket[K, V]) refresh(p *LRUCache[K, V], ns *Namespace[K, V], k K, h uintptr, tItem LRUItem[K, V]) {
+	caller := p.callerOf(ns)
+	if caller == nil {
+		return
+	}
+	t := tItem.expiration - b.now()
 	if t > 0 && t*100/tItem.duration < 30 {
 		if p.randfunc != nil && !p.randfunc(t, tItem.duration) {
 			return
 		}
-		v, err := p.caller(k)
+		v, err := caller(k)
+		ns.loaded(err)
 		if err == nil {
-			b.SetWithExp(k, v, h, p.defaultDuration)
+			b.put(h, p.item(ns, k, v, p.durationOf(ns), nil), p.wal.Load())
 		}
 	}
 }
 
-func (b *LRUBucket[K, V]) deleteExpired() {
-	now := time.Now().UnixNano()
-	i := 0
+// deleteExpired look at up to basic.ScanNums entries, removing up to
+// basic.DeleteNums expired ones, logging them to w, if any, and up to
+// basic.StaleDeleteNums entries invalidated by InvalidateAll or Bump
+func (b *LRUBucket[K, V]) deleteExpired(w *basic.WAL[K, V]) {
+	now := b.now()
+	i, j, n := 0, 0, 0
+	var seq uint64
 	b.mu.Lock()
+	b.drain()
+	epoch := b.stamps.epoch.Load()
 	for k, item := range b.items {
-		if i >= basic.DeleteNums {
+		if n >= basic.ScanNums || (i >= basic.DeleteNums && j >= basic.StaleDeleteNums) {
 			break
 		}
-		if item.expiration < now {
+		n++
+		if item.stale(epoch) {
+			if j < basic.StaleDeleteNums {
+				j++
+				b.remove(k, item)
+			}
+		} else if i < basic.DeleteNums && item.expiration < now {
 			i++
-			b.remove(item.p)
-			delete(b.items, k)
+			b.remove(k, item)
+			if item.ns == nil {
+				seq = w.Append(basic.OpExpire, &basic.Entry[K, V]{Key: item.key})
+			}
 		}
 	}
 	b.mu.Unlock()
+	w.Wait(seq)
+}
+
+// record buffer an access to e. Readers never take the write lock for it:
+// the order list is only reordered once a stripe fills up and the lock
+// happens to be free, otherwise the buffered accesses wait for the next
+// writer.
+func (b *LRUBucket[K, V]) record(n *node) {
+	if n == nil {
+		return
+	}
+	if b.reads[rand.Uint32()&(readStripes-1)].add(n) && b.mu.TryLock() {
+		b.drain()
+		b.mu.Unlock()
+	}
 }
 
-func (b *LRUBucket[K, V]) move(e *list.Element) {
-	if e != nil {
-		b.order.MoveToFront(e)
+// drain replay the buffered accesses, the write lock must be held
+func (b *LRUBucket[K, V]) drain() {
+	if b.moved != nil {
+		return
+	}
+	for i := range b.reads {
+		b.reads[i].drain(b.policy.hit)
 	}
 }
 
-func (b *LRUBucket[K, V]) add(key K) *list.Element {
-	return b.order.PushFront(key)
+// stamps are the counters shared by every shard of a cache and its
+// clock, see basic.PartitionCache
+type stamps struct {
+	epoch   atomic.Uint64
+	version atomic.Uint64
+	clock   basic.Clock
 }
 
-func (b *LRUBucket[K, V]) remove(e *list.Element) {
-	b.order.Remove(e)
+// lruTable is one generation of shards, see basic.PartitionCache
+type lruTable[K comparable, V any] struct {
+	mask    uintptr
+	buckets []LRUBucket[K, V]
+}
+
+func newLRUTable[K comparable, V any](n int, size uint64, o basic.Options, idx *basic.KeyIndex[K], stamps *stamps, replicas *basic.HotReplicas[K, LRUItem[K, V]]) *lruTable[K, V] {
+	t := &lruTable[K, V]{
+		mask:    uintptr(n - 1),
+		buckets: make([]LRUBucket[K, V], n),
+	}
+	for i := range t.buckets {
+		t.buckets[i].initBucket(size/uint64(n)+1, o, idx, stamps, replicas)
+	}
+	return t
+}
+
+func (t *lruTable[K, V]) bucket(h uintptr) *LRUBucket[K, V] {
+	return &(t.buckets[h&t.mask])
 }
 
 // LRUCache
 type LRUCache[K comparable, V any] struct {
 	noCopy
 	defaultDuration time.Duration
-	mask            uintptr
-	buckets         []LRUBucket[K, V]
+	size            uint64
+	options         basic.Options
+	table           atomic.Pointer[lruTable[K, V]]
+	resizeMu        sync.Mutex
 	randfunc        func(int64, int64) bool
 	caller          func(K) (V, error)
+	bulk            *basic.BulkLoader[K, V]
+	hot             *basic.HotKeyTracker[K]
+	replicas        *basic.HotReplicas[K, LRUItem[K, V]]
+	idx             *basic.KeyIndex[K]
+	stamps          stamps
+	codec           basic.Codec[K, V]
+	wal             atomic.Pointer[basic.WAL[K, V]]
 	janitor         *Janitor
+	nsMu            sync.Mutex
+	namespaces      map[string]*Namespace[K, V]
 }
 
-// NewLRUCache new cache
-func NewLRUCache[K comparable, V any](size uint64) *LRUCache[K, V] {
+// NewLRUCache new cache holding about size entries.
+// The eviction policy is LRU unless basic.WithType selects WTinyLFU,
+// S3FIFO, TwoQ or SLRU. basic.WithAdmission puts a TinyLFU filter in
+// front of LRU.
+func NewLRUCache[K comparable, V any](size uint64, opts ...basic.Option) *LRUCache[K, V] {
+	o := basic.NewOptions(opts...)
 	c := &LRUCache[K, V]{
-		mask:            uintptr(basic.InitialSize - 1),
-		buckets:         make([]LRUBucket[K, V], basic.InitialSize),
+		size:            size,
+		options:         o,
 		defaultDuration: 10 * time.Second,
 		randfunc:        randfunc,
+		idx:             basic.NewKeyIndex[K](o.PrefixIndex),
+		hot:             basic.NewHotKeyTracker[K](o.HotKeys, o.HotKeySample),
+		replicas:        basic.NewHotReplicas[K, LRUItem[K, V]](o.HotKeyReplicas),
 	}
-	c.initBucket(size)
-	j := NewJanitor(1*time.Second, c.deleteExpired)
+	c.stamps.clock = o.Clock
+	c.table.Store(newLRUTable[K, V](o.Shards, size, o, c.idx, &c.stamps, c.replicas))
+	j := NewClockJanitor(o.Clock, 1*time.Second, c.deleteExpired)
 	runtime.SetFinalizer(c, (*LRUCache[K, V]).clean)
 	c.janitor = j
 	return c
 }
 
-func (c *LRUCache[K, V]) initBucket(size uint64) {
-	for i := range c.buckets {
-		c.buckets[i].initBucket(size/basic.InitialSize + 1)
-	}
-}
 func (c *LRUCache[K, V]) clean() {
 	fmt.Println("lru stop")
+	c.Close()
+	t := c.table.Load()
+	for i := range t.buckets {
+		t.buckets[i].clean()
+	}
+}
+
+// Close stop the janitor of the cache, see the Close of
+// basic.PartitionCache
+func (c *LRUCache[K, V]) Close() {
 	if c.janitor != nil {
 		c.janitor.Stop()
 		c.janitor = nil
 	}
-	for i := range c.buckets {
-		c.buckets[i].clean()
-	}
 }
 
 // WithCallback set callback
@@ -198,26 +461,57 @@ func (c *LRUCache[K, V]) WithCallback(call func(K) (V, error)) {
 func (c *LRUCache[K, V]) WithRandfunc(call func(int64, int64) bool) {
 	c.randfunc = call
 }
+
+// Shards return the current number of shards
+func (c *LRUCache[K, V]) Shards() int {
+	return len(c.table.Load().buckets)
+}
+
+// Capacity return the number of entries the cache holds at most
+func (c *LRUCache[K, V]) Capacity() int {
+	return int(c.size)
+}
+
+// Resize change the number of shards to n, rounded up to a power of two.
+// The capacity is split evenly over the new shards. Entries are migrated
+// one shard at a time, only the shard being moved is locked.
+func (c *LRUCache[K, V]) Resize(n int) {
+	c.resizeMu.Lock()
+	defer c.resizeMu.Unlock()
+	old := c.table.Load()
+	n = basic.ShardCount(n)
+	if n == len(old.buckets) {
+		return
+	}
+	next := newLRUTable[K, V](n, c.size, c.options, c.idx, &c.stamps, c.replicas)
+	for i := range old.buckets {
+		old.buckets[i].migrate(next)
+	}
+	c.table.Store(next)
+}
+
 func ehash(i interface{}) uintptr {
-	return nilinterhash(noescape(unsafe.Pointer(&i)), 0xdeadbeef)
+	return nilinterhash(unsafe.Pointer(&i), 0xdeadbeef)
 }
 
+//go:noescape
 //go:linkname nilinterhash runtime.nilinterhash
 func nilinterhash(p unsafe.Pointer, h uintptr) uintptr
 
-//go:nocheckptr
-//go:nosplit
-func noescape(p unsafe.Pointer) unsafe.Pointer {
-	x := uintptr(p)
-	return unsafe.Pointer(x ^ 0)
-}
-
+// Get value of k, loading it with the callback on a miss
+// error maybe not found, timeout
 func (c *LRUCache[K, V]) Get(k K) (r V, err error) {
 	hash := ehash(k)
-	i := hash & c.mask
-	b := &(c.buckets[i])
-	r, err = b.Get(c, k, hash)
-	return
+	t := c.table.Load()
+	if !c.hot.Observe(k, int(hash&t.mask), len(t.buckets)) {
+		// unsampled Gets of a replicated key skip the shard lock
+		if item, ok := c.replicas.Load(k); ok && item.epoch == c.stamps.epoch.Load() && !item.expiredAt(c.stamps.clock.Now()) {
+			item.p.hits.Add(1)
+			t.bucket(hash).refresh(c, nil, k, hash, *item)
+			return item.obj, nil
+		}
+	}
+	return t.bucket(hash).Get(c, nil, k, hash)
 }
 
 func (c *LRUCache[K, V]) Set(k K, v V) {
@@ -227,16 +521,36 @@ func (c *LRUCache[K, V]) Set(k K, v V) {
 // SetWithExp actively set LRUBucket value
 func (c *LRUCache[K, V]) SetWithExp(k K, v V, dur time.Duration) {
 	hash := ehash(k)
-	i := hash & c.mask
-	b := &(c.buckets[i])
-	p, exsit := b.SetWithExp(k, v, hash, dur)
-	if exsit {
-		b.move(p)
+	b := c.table.Load().bucket(hash)
+	b.SetWithExp(k, v, hash, dur, c.wal.Load())
+}
+
+// Delete remove k
+func (c *LRUCache[K, V]) Delete(k K) {
+	hash := ehash(k)
+	c.table.Load().bucket(hash).delete(k, hash, nil, basic.OpDelete, nil, c.wal.Load())
+}
+
+// InvalidateAll drop every entry in O(1), namespaces included: they
+// become misses at once and the janitor or the eviction policy reclaims
+// them later. It is logged to the WAL, if any.
+func (c *LRUCache[K, V]) InvalidateAll() {
+	c.stamps.epoch.Add(1)
+	c.nsMu.Lock()
+	for _, ns := range c.namespaces {
+		ns.Bump()
 	}
+	c.nsMu.Unlock()
+	w := c.wal.Load()
+	w.Wait(w.Append(basic.OpClear, &basic.Entry[K, V]{}))
 }
 
+// deleteExpired is the janitor pass: it reclaims expired and invalidated
+// entries and picks the hot keys to replicate
 func (c *LRUCache[K, V]) deleteExpired() {
-	for i := range c.buckets {
-		c.buckets[i].deleteExpired()
+	t := c.table.Load()
+	for i := range t.buckets {
+		t.buckets[i].deleteExpired(c.wal.Load())
 	}
+	c.replicas.Follow(c.hot)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"runtime"
+	"stablecache/basic"
 	"strconv"
 	"testing"
 	"time"
@@ -28,6 +29,35 @@ func TestLRUCache(t *testing.T) {
 	runtime.GC()
 }
 
+func TestLRUCacheResize(t *testing.T) {
+	Convey("resize keeps entries and their recency", t, func() {
+		cache := NewLRUCache[int, int](1000, basic.WithShards(1))
+		So(cache.Shards(), ShouldEqual, 1)
+		for i := 0; i < 100; i++ {
+			cache.Set(i, i)
+		}
+		cache.Resize(8)
+		So(cache.Shards(), ShouldEqual, 8)
+		for i := 0; i < 100; i++ {
+			value, err := cache.Get(i)
+			So(err, ShouldBeNil)
+			So(value, ShouldEqual, i)
+		}
+		t := cache.table.Load()
+		for i := range t.buckets {
+			b := &t.buckets[i]
+			n, last := 0, -1
+			b.policy.each(func(h uintptr) {
+				k := b.items[h].key
+				So(k, ShouldBeGreaterThan, last)
+				last = k
+				n++
+			})
+			So(n, ShouldEqual, len(b.items))
+		}
+	})
+}
+
 func BenchmarkGetLRUCache(b *testing.B) {
 	cache := NewLRUCache[string, []byte](defaultSize)
 	cache.WithCallback(getmessage)
@@ -0,0 +1,104 @@
+package stablecache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUNamespace(t *testing.T) {
+	Convey("lru namespaces share the shards and flush in O(1)", t, func() {
+		cache := NewLRUCache[int, int](1000, basic.WithShards(8))
+		a, b := cache.Namespace("a"), cache.Namespace("b")
+		So(cache.Namespace("b"), ShouldEqual, b)
+		for i := 0; i < 100; i++ {
+			cache.Set(i, -i)
+			a.Set(i, i)
+			b.Set(i, i*2)
+		}
+		v, err := a.Get(3)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 3)
+		a.Flush()
+		So(a.Len(), ShouldEqual, 0)
+		_, err = a.Get(3)
+		So(err, ShouldEqual, NotFound)
+		v, _ = b.Get(3)
+		So(v, ShouldEqual, 6)
+		v, _ = cache.Get(3)
+		So(v, ShouldEqual, -3)
+		// janitor passes reclaim what the flush left behind
+		for i := 0; i < 10; i++ {
+			cache.deleteExpired()
+		}
+		n := 0
+		t := cache.table.Load()
+		for i := range t.buckets {
+			n += len(t.buckets[i].items)
+		}
+		So(n, ShouldEqual, 200)
+		b.Delete(3)
+		So(b.Len(), ShouldEqual, 99)
+	})
+
+	Convey("lru namespaces have their own loader, ttl, quota and stats", t, func() {
+		cache := NewLRUCache[int, int](1000)
+		ns := cache.Namespace("x")
+		ns.WithTTL(time.Minute)
+		ns.WithQuota(2)
+		ns.WithCallback(func(k int) (int, error) {
+			if k < 0 {
+				return 0, errors.New("negative")
+			}
+			return k + 100, nil
+		})
+		v, err := ns.Get(1)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 101)
+		_, err = ns.Get(-1)
+		So(err, ShouldEqual, NotFound)
+		ns.Set(2, 2)
+		ns.Set(3, 3)
+		ns.Set(2, 20)
+		_, err = cache.Get(1)
+		So(err, ShouldEqual, NotFound)
+		s := ns.Stats()
+		So(s.Misses, ShouldEqual, 2)
+		So(s.Loads, ShouldEqual, 2)
+		So(s.LoadErrors, ShouldEqual, 1)
+		So(s.Rejected, ShouldEqual, 1)
+		So(s.Len, ShouldEqual, 2)
+		v, err = ns.Get(2)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 20)
+		So(ns.Stats().Hits, ShouldEqual, 1)
+	})
+	Convey("lru invalidate all hides the cache and its namespaces", t, func() {
+		cache := NewLRUCache[int, int](1000, basic.WithShards(4))
+		ns := cache.Namespace("n")
+		for i := 0; i < 50; i++ {
+			cache.Set(i, i)
+			ns.Set(i, i)
+		}
+		cache.InvalidateAll()
+		_, err := cache.Get(1)
+		So(err, ShouldEqual, NotFound)
+		_, err = ns.Get(1)
+		So(err, ShouldEqual, NotFound)
+		ns.Set(1, 1)
+		So(ns.Len(), ShouldEqual, 1)
+		for i := 0; i < 10; i++ {
+			cache.deleteExpired()
+		}
+		n := 0
+		t := cache.table.Load()
+		for i := range t.buckets {
+			n += len(t.buckets[i].items)
+		}
+		So(n, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,60 @@
+package stablecache
+
+import (
+	"stablecache/basic"
+	"time"
+)
+
+// Peek read k without side effects: no promotion, no hit, no loader and
+// no refresh
+// error maybe not found, timeout
+func (c *LRUCache[K, V]) Peek(k K) (r V, err error) {
+	item, ok := c.peek(k)
+	if !ok {
+		return r, NotFound
+	}
+	if item.expiredAt(c.stamps.clock.Now()) {
+		return item.obj, Timeout
+	}
+	return item.obj, nil
+}
+
+// GetEntry return k with its metadata, without side effects like Peek.
+// An expired entry is returned as Stale, error is only not found.
+func (c *LRUCache[K, V]) GetEntry(k K) (basic.EntryInfo[K, V], error) {
+	item, ok := c.peek(k)
+	if !ok {
+		return basic.EntryInfo[K, V]{}, NotFound
+	}
+	return basic.NewEntryInfo(c.stamps.clock, item.key, item.obj, item.expiration, item.duration, item.created, item.p.hits.Load()), nil
+}
+
+// Touch give k a new ttl without rewriting its value or promoting it, it
+// reports whether k was there. Expired entries are not revived.
+func (c *LRUCache[K, V]) Touch(k K, ttl time.Duration) bool {
+	h := ehash(k)
+	w := c.wal.Load()
+	b := c.table.Load().bucket(h).lock(h)
+	i, ok := b.items[h]
+	if !ok || !i.owned(k, nil, b.stamps.epoch.Load()) || i.expiredAt(b.now()) {
+		b.mu.Unlock()
+		return false
+	}
+	i.expiration = b.now() + int64(ttl)
+	i.duration = int64(ttl)
+	b.items[h] = i
+	b.replicas.Store(k, i)
+	seq := w.Append(basic.OpSet, &basic.Entry[K, V]{Key: k, Value: i.obj, Expiration: i.expiration, Duration: i.duration, Tags: i.tags})
+	b.mu.Unlock()
+	w.Wait(seq)
+	return true
+}
+
+func (c *LRUCache[K, V]) peek(k K) (LRUItem[K, V], bool) {
+	h := ehash(k)
+	b := c.table.Load().bucket(h).rlock(h)
+	item, ok := b.items[h]
+	epoch := b.stamps.epoch.Load()
+	b.mu.RUnlock()
+	return item, ok && item.owned(k, nil, epoch)
+}
@@ -0,0 +1,53 @@
+package stablecache
+
+import (
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUPeek(t *testing.T) {
+	Convey("lru peek does not promote, get does", t, func() {
+		for _, read := range []string{"peek", "get"} {
+			cache := NewLRUCache[int, int](3, basic.WithShards(1))
+			for i := 0; i < 4; i++ {
+				cache.Set(i, i)
+			}
+			if read == "peek" {
+				v, err := cache.Peek(0)
+				So(err, ShouldBeNil)
+				So(v, ShouldEqual, 0)
+			} else {
+				cache.Get(0)
+			}
+			cache.Set(4, 4)
+			_, err := cache.Peek(0)
+			if read == "peek" {
+				So(err, ShouldEqual, NotFound)
+			} else {
+				So(err, ShouldBeNil)
+			}
+		}
+	})
+
+	Convey("lru get entry and touch", t, func() {
+		cache := NewLRUCache[string, int](100)
+		cache.SetWithExp("a", 1, time.Millisecond)
+		cache.Get("a")
+		So(cache.Touch("a", time.Minute), ShouldBeTrue)
+		time.Sleep(2 * time.Millisecond)
+		e, err := cache.GetEntry("a")
+		So(err, ShouldBeNil)
+		So(e.Hits, ShouldEqual, 1)
+		So(e.Stale, ShouldBeFalse)
+		So(e.Duration, ShouldEqual, time.Minute)
+		cache.Resize(8)
+		e, _ = cache.GetEntry("a")
+		So(e.Hits, ShouldEqual, 1)
+		_, err = cache.GetEntry("b")
+		So(err, ShouldEqual, NotFound)
+	})
+}
@@ -0,0 +1,281 @@
+package stablecache
+
+import (
+	"container/list"
+	"stablecache/basic"
+	"sync/atomic"
+)
+
+// policy decides the order in which the entries of an LRUBucket are
+// evicted. All methods but touch are called with the bucket write lock
+// held.
+type policy interface {
+	// add place a new entry
+	add(h uintptr) *node
+	// touch record an access to n under the read lock only, so it may
+	// only change n atomically. It reports false when the access has to
+	// go through hit instead.
+	touch(n *node) bool
+	// hit record an access to n
+	hit(n *node)
+	// remove forget n
+	remove(n *node)
+	// evict drop one entry from the policy and return its hash
+	evict() (uintptr, bool)
+	// each walk the entries from the first to be evicted to the last
+	each(fn func(h uintptr))
+}
+
+// node is the policy bookkeeping of one entry. e is nil once the entry
+// left the policy, buffered hits on it are then ignored.
+type node struct {
+	h    uintptr
+	seg  int8
+	e    *list.Element
+	freq atomic.Int32
+	// hits counts the reads of the entry, for GetEntry
+	hits atomic.Uint64
+}
+
+// newPolicy build the policy selected by o for a bucket of size entries.
+// LFU and ARC are not implemented and fall back to LRU.
+func newPolicy(o basic.Options, size uint64) policy {
+	switch o.Type {
+	case basic.WTinyLFU:
+		return newWTinyLFU(size, o)
+	case basic.S3FIFO:
+		return newS3FIFO(size, o)
+	case basic.TwoQ:
+		return newTwoQ(size, o)
+	case basic.SLRU:
+		return newSLRU(size, o)
+	default:
+		p := &lruPolicy{order: list.New()}
+		if o.Admission {
+			p.admit = basic.NewTinyLFU(int(size))
+		}
+		return p
+	}
+}
+
+// ratio return the share r of size, at least 1, def is used when r is unset
+func ratio(size uint64, r, def float64) int {
+	if r <= 0 || r >= 1 {
+		r = def
+	}
+	n := int(float64(size) * r)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func walk(l *list.List, fn func(h uintptr)) {
+	for e := l.Back(); e != nil; e = e.Prev() {
+		fn(e.Value.(*node).h)
+	}
+}
+
+// move n from the list from to the front of to, which holds segment seg
+func move(n *node, from, to *list.List, seg int8) {
+	if from == to {
+		to.MoveToFront(n.e)
+		return
+	}
+	from.Remove(n.e)
+	n.seg = seg
+	n.e = to.PushFront(n)
+}
+
+// ghost remember the hashes of recently evicted entries, oldest first out
+type ghost struct {
+	order *list.List
+	keys  map[uintptr]*list.Element
+	max   int
+}
+
+func newGhost(max int) *ghost {
+	if max < 1 {
+		max = 1
+	}
+	return &ghost{
+		order: list.New(),
+		keys:  make(map[uintptr]*list.Element),
+		max:   max,
+	}
+}
+
+func (g *ghost) add(h uintptr) {
+	if _, ok := g.keys[h]; ok {
+		return
+	}
+	g.keys[h] = g.order.PushFront(h)
+	if g.order.Len() > g.max {
+		e := g.order.Back()
+		g.order.Remove(e)
+		delete(g.keys, e.Value.(uintptr))
+	}
+}
+
+// take forget h and report whether it was there
+func (g *ghost) take(h uintptr) bool {
+	e, ok := g.keys[h]
+	if ok {
+		g.order.Remove(e)
+		delete(g.keys, h)
+	}
+	return ok
+}
+
+// lruPolicy evict the least recently used entry. With admit set, a new
+// entry only takes the place of the victim when TinyLFU has seen it more
+// often, otherwise the new entry itself is dropped.
+type lruPolicy struct {
+	order *list.List
+	admit *basic.TinyLFU
+}
+
+func (p *lruPolicy) add(h uintptr) *node {
+	if p.admit != nil {
+		p.admit.Increment(uint64(h))
+	}
+	n := &node{h: h}
+	n.e = p.order.PushFront(n)
+	return n
+}
+
+func (p *lruPolicy) touch(n *node) bool {
+	return false
+}
+
+func (p *lruPolicy) hit(n *node) {
+	if n.e == nil {
+		return
+	}
+	if p.admit != nil {
+		p.admit.Increment(uint64(n.h))
+	}
+	p.order.MoveToFront(n.e)
+}
+
+func (p *lruPolicy) remove(n *node) {
+	if n.e != nil {
+		p.order.Remove(n.e)
+		n.e = nil
+	}
+}
+
+func (p *lruPolicy) evict() (uintptr, bool) {
+	e := p.order.Back()
+	if e == nil {
+		return 0, false
+	}
+	victim := e.Value.(*node)
+	if p.admit != nil {
+		candidate := p.order.Front().Value.(*node)
+		if candidate != victim && !p.admit.Admit(uint64(candidate.h), uint64(victim.h)) {
+			victim = candidate
+		}
+	}
+	p.remove(victim)
+	return victim.h, true
+}
+
+func (p *lruPolicy) each(fn func(h uintptr)) {
+	walk(p.order, fn)
+}
+
+const (
+	segWindow int8 = iota
+	segProbation
+	segProtected
+)
+
+// wTinyLFU is the W-TinyLFU policy: new entries go through a small LRU
+// window, the entries falling out of it compete through TinyLFU for a
+// place in the main space, a segmented LRU of probation and protected.
+type wTinyLFU struct {
+	slru
+	window    *list.List
+	maxWindow int
+	sketch    *basic.TinyLFU
+	// candidate is the last entry pushed out of the window, it has to
+	// beat the probation victim to stay
+	candidate *node
+}
+
+func newWTinyLFU(size uint64, o basic.Options) *wTinyLFU {
+	window := ratio(size, o.WindowRatio, 0.01)
+	return &wTinyLFU{
+		slru:      *newSLRU(size-uint64(window), o),
+		window:    list.New(),
+		maxWindow: window,
+		sketch:    basic.NewTinyLFU(int(size)),
+	}
+}
+
+func (p *wTinyLFU) list(seg int8) *list.List {
+	if seg == segWindow {
+		return p.window
+	}
+	return p.slru.list(seg)
+}
+
+func (p *wTinyLFU) add(h uintptr) *node {
+	p.sketch.Increment(uint64(h))
+	n := &node{h: h, seg: segWindow}
+	n.e = p.window.PushFront(n)
+	if p.window.Len() > p.maxWindow {
+		p.candidate = p.window.Back().Value.(*node)
+		move(p.candidate, p.window, p.probation, segProbation)
+	}
+	return n
+}
+
+func (p *wTinyLFU) touch(n *node) bool {
+	return false
+}
+
+func (p *wTinyLFU) hit(n *node) {
+	if n.e == nil {
+		return
+	}
+	p.sketch.Increment(uint64(n.h))
+	if n.seg == segWindow {
+		p.window.MoveToFront(n.e)
+		return
+	}
+	p.promote(n)
+}
+
+func (p *wTinyLFU) remove(n *node) {
+	if n.e != nil {
+		p.list(n.seg).Remove(n.e)
+		n.e = nil
+	}
+}
+
+func (p *wTinyLFU) evict() (uintptr, bool) {
+	victim := p.victim()
+	if victim == nil {
+		e := p.window.Back()
+		if e == nil {
+			return 0, false
+		}
+		victim = e.Value.(*node)
+	}
+	candidate := p.candidate
+	p.candidate = nil
+	if candidate != nil && candidate != victim && candidate.e != nil && candidate.seg == segProbation &&
+		!p.sketch.Admit(uint64(candidate.h), uint64(victim.h)) {
+		victim = candidate
+	}
+	p.remove(victim)
+	return victim.h, true
+}
+
+func (p *wTinyLFU) each(fn func(h uintptr)) {
+	walk(p.probation, fn)
+	walk(p.window, fn)
+	walk(p.protected, fn)
+}
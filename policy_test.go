@@ -0,0 +1,88 @@
+package stablecache
+
+import (
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// scan warm up a hot set, run a scan of cold keys over it while the hot
+// set keeps being read now and then, and return how many hot keys survived
+func scan(cache *LRUCache[int, int]) int {
+	get := func(i int) {
+		if _, err := cache.Get(i); err != nil {
+			cache.Set(i, i)
+		}
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			get(i)
+		}
+	}
+	for i := 1000; i < 5000; i++ {
+		cache.Set(i, i)
+		if i%10 == 0 {
+			get(i / 10 % 50)
+		}
+	}
+	alive := 0
+	for i := 0; i < 50; i++ {
+		if _, err := cache.Get(i); err == nil {
+			alive++
+		}
+	}
+	return alive
+}
+
+func TestPolicy(t *testing.T) {
+	Convey("lru evicts the least recently used entry", t, func() {
+		cache := NewLRUCache[int, int](3, basic.WithShards(1))
+		So(cache.table.Load().buckets[0].size, ShouldEqual, 4)
+		for i := 0; i < 4; i++ {
+			cache.Set(i, i)
+		}
+		cache.Get(0)
+		cache.Set(4, 4)
+		_, err := cache.Get(1)
+		So(err, ShouldEqual, NotFound)
+		_, err = cache.Get(0)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("a scan flushes plain lru", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1))
+		So(scan(cache), ShouldBeLessThan, 20)
+	})
+
+	Convey("tinylfu admission keeps the hot set", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1), basic.WithAdmission())
+		So(scan(cache), ShouldBeGreaterThan, 45)
+	})
+
+	Convey("w-tinylfu keeps the hot set", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1), basic.WithType(WTinyLFU))
+		So(scan(cache), ShouldBeGreaterThan, 45)
+	})
+
+	Convey("w-tinylfu segments stay within their sizes", t, func() {
+		p := newWTinyLFU(100, basic.Options{})
+		nodes := map[uintptr]*node{}
+		for h := uintptr(0); h < 100; h++ {
+			nodes[h] = p.add(h)
+		}
+		for h := uintptr(0); h < 100; h++ {
+			p.hit(nodes[h])
+		}
+		So(p.window.Len(), ShouldEqual, p.maxWindow)
+		So(p.protected.Len(), ShouldBeLessThanOrEqualTo, p.maxProtected)
+		So(p.window.Len()+p.probation.Len()+p.protected.Len(), ShouldEqual, 100)
+		n := 0
+		p.each(func(uintptr) { n++ })
+		So(n, ShouldEqual, 100)
+		p.remove(nodes[5])
+		p.hit(nodes[5])
+		So(p.window.Len()+p.probation.Len()+p.protected.Len(), ShouldEqual, 99)
+	})
+}
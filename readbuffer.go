@@ -0,0 +1,50 @@
+package stablecache
+
+import "sync/atomic"
+
+const (
+	readStripes    = 4
+	readBufferSize = 16
+	readBufferMask = readBufferSize - 1
+)
+
+// readBuffer is a lossy ring buffer of accessed policy nodes.
+// Any number of readers may add to it, it is only drained by the holder of
+// the bucket write lock. When it is full new accesses are dropped: the
+// order list only has to be roughly right for eviction to work.
+type readBuffer struct {
+	head  atomic.Uint32
+	tail  atomic.Uint32
+	slots [readBufferSize]atomic.Pointer[node]
+}
+
+// add record n, it reports whether the buffer is full and should be drained
+func (r *readBuffer) add(n *node) bool {
+	head := r.head.Load()
+	tail := r.tail.Load()
+	size := tail - head
+	if size >= readBufferSize {
+		return true
+	}
+	if r.tail.CompareAndSwap(tail, tail+1) {
+		r.slots[tail&readBufferMask].Store(n)
+		return size+1 >= readBufferSize
+	}
+	return false
+}
+
+// drain pass every recorded node to fn in the order they were added.
+// A slot that is reserved but not yet written stops the drain, it will be
+// picked up by the next one.
+func (r *readBuffer) drain(fn func(*node)) {
+	head := r.head.Load()
+	tail := r.tail.Load()
+	for ; head != tail; head++ {
+		n := r.slots[head&readBufferMask].Swap(nil)
+		if n == nil {
+			break
+		}
+		fn(n)
+	}
+	r.head.Store(head)
+}
@@ -0,0 +1,90 @@
+package stablecache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadBuffer(t *testing.T) {
+	Convey("ring buffer keeps order and drops when full", t, func() {
+		var r readBuffer
+		var elems []*node
+		for i := 0; i < readBufferSize+4; i++ {
+			elems = append(elems, &node{h: uintptr(i)})
+		}
+		full := false
+		for _, e := range elems[:readBufferSize] {
+			full = r.add(e)
+		}
+		So(full, ShouldBeTrue)
+		So(r.add(elems[readBufferSize]), ShouldBeTrue)
+
+		var got []*node
+		r.drain(func(n *node) { got = append(got, n) })
+		So(got, ShouldResemble, elems[:readBufferSize])
+		So(r.add(elems[readBufferSize]), ShouldBeFalse)
+	})
+}
+
+func TestLRUBufferedReads(t *testing.T) {
+	Convey("buffered reads reorder the list once drained", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1))
+		for i := 0; i < 10; i++ {
+			cache.Set(i, i)
+		}
+		_, err := cache.Get(0)
+		So(err, ShouldBeNil)
+		b := &cache.table.Load().buckets[0]
+		// the read is only buffered, the next write applies it
+		cache.Set(10, 10)
+		var order []uintptr
+		b.policy.each(func(h uintptr) { order = append(order, h) })
+		So(order[len(order)-1], ShouldEqual, ehash(10))
+		So(order[len(order)-2], ShouldEqual, ehash(0))
+	})
+
+	Convey("readers never wait for the write lock to record an access", t, func() {
+		cache := NewLRUCache[string, int](1000, basic.WithShards(1))
+		for i := 0; i < 100; i++ {
+			cache.Set(strconv.Itoa(i), i)
+		}
+		b := &cache.table.Load().buckets[0]
+		// a long reader keeps the write lock out: full buffers must be
+		// left for later instead of waiting for it
+		b.mu.RLock()
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 10000; i++ {
+					cache.Get(strconv.Itoa(i % 100))
+				}
+			}()
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			So("readers waited for the write lock", ShouldBeEmpty)
+		}
+		b.mu.RUnlock()
+		<-done
+		b.mu.Lock()
+		b.drain()
+		n := 0
+		b.policy.each(func(uintptr) { n++ })
+		So(n, ShouldEqual, 100)
+		b.mu.Unlock()
+	})
+}
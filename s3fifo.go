@@ -0,0 +1,108 @@
+package stablecache
+
+import (
+	"container/list"
+	"stablecache/basic"
+)
+
+const (
+	segSmall int8 = iota
+	segMain
+)
+
+// maxFreq is where the 2-bit access counter of S3-FIFO saturates
+const maxFreq = 3
+
+// s3fifo is the S3-FIFO policy: new entries go into a small probationary
+// FIFO, the ones hit more than once there move on to the main FIFO, the
+// others leave only their hash in a ghost FIFO. A key found in the ghost
+// goes straight to main. Entries never move on a hit, only their counter
+// is bumped, so hits need no write lock at all.
+type s3fifo struct {
+	small    *list.List
+	main     *list.List
+	ghost    *ghost
+	maxSmall int
+}
+
+func newS3FIFO(size uint64, o basic.Options) *s3fifo {
+	small := ratio(size, o.WindowRatio, 0.1)
+	return &s3fifo{
+		small:    list.New(),
+		main:     list.New(),
+		ghost:    newGhost(int(size) - small),
+		maxSmall: small,
+	}
+}
+
+func (p *s3fifo) list(seg int8) *list.List {
+	if seg == segMain {
+		return p.main
+	}
+	return p.small
+}
+
+func (p *s3fifo) add(h uintptr) *node {
+	n := &node{h: h, seg: segSmall}
+	if p.ghost.take(h) {
+		n.seg = segMain
+	}
+	n.e = p.list(n.seg).PushFront(n)
+	return n
+}
+
+func (p *s3fifo) touch(n *node) bool {
+	for {
+		f := n.freq.Load()
+		if f >= maxFreq || n.freq.CompareAndSwap(f, f+1) {
+			return true
+		}
+	}
+}
+
+func (p *s3fifo) hit(n *node) {
+	p.touch(n)
+}
+
+func (p *s3fifo) remove(n *node) {
+	if n.e != nil {
+		p.list(n.seg).Remove(n.e)
+		n.e = nil
+	}
+}
+
+func (p *s3fifo) evict() (uintptr, bool) {
+	for {
+		if p.small.Len() >= p.maxSmall || p.main.Len() == 0 {
+			e := p.small.Back()
+			if e == nil {
+				return 0, false
+			}
+			n := e.Value.(*node)
+			if n.freq.Load() > 1 {
+				p.small.Remove(e)
+				n.seg = segMain
+				n.freq.Store(0)
+				n.e = p.main.PushFront(n)
+				continue
+			}
+			p.remove(n)
+			p.ghost.add(n.h)
+			return n.h, true
+		}
+		e := p.main.Back()
+		n := e.Value.(*node)
+		if n.freq.Load() > 0 {
+			n.freq.Add(-1)
+			p.main.MoveToFront(e)
+			continue
+		}
+		p.remove(n)
+		return n.h, true
+	}
+}
+
+func (p *s3fifo) each(fn func(h uintptr)) {
+	walk(p.small, fn)
+	walk(p.main, fn)
+}
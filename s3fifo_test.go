@@ -0,0 +1,70 @@
+package stablecache
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestS3FIFO(t *testing.T) {
+	Convey("one-hit entries leave through the ghost, ghost hits go to main", t, func() {
+		p := newS3FIFO(10, basic.Options{})
+		So(p.maxSmall, ShouldEqual, 1)
+		a := p.add(1)
+		p.add(2)
+		h, ok := p.evict()
+		So(ok, ShouldBeTrue)
+		So(h, ShouldEqual, 1)
+		So(a.e, ShouldBeNil)
+		So(p.ghost.keys, ShouldContainKey, uintptr(1))
+		a = p.add(1)
+		So(a.seg, ShouldEqual, segMain)
+		So(p.ghost.keys, ShouldNotContainKey, uintptr(1))
+	})
+
+	Convey("entries hit in small are promoted instead of evicted", t, func() {
+		p := newS3FIFO(10, basic.Options{})
+		a := p.add(1)
+		p.touch(a)
+		p.touch(a)
+		p.add(2)
+		h, _ := p.evict()
+		So(h, ShouldEqual, 2)
+		So(a.seg, ShouldEqual, segMain)
+		for i := 0; i < 10; i++ {
+			p.touch(a)
+		}
+		So(a.freq.Load(), ShouldEqual, maxFreq)
+	})
+
+	Convey("s3fifo keeps the hot set through a scan", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1), basic.WithType(S3FIFO))
+		So(scan(cache), ShouldBeGreaterThan, 45)
+	})
+	Convey("reads touch entries safely while the cache is resized", t, func() {
+		cache := NewLRUCache[int, int](1000, basic.WithShards(2), basic.WithType(S3FIFO))
+		for i := 0; i < 500; i++ {
+			cache.Set(i, i)
+		}
+		var wg sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 5000; i++ {
+					cache.Get(i % 500)
+					cache.GetMany(context.Background(), []int{i % 500})
+				}
+			}()
+		}
+		for _, n := range []int{4, 8, 2, 16, 1} {
+			cache.Resize(n)
+		}
+		wg.Wait()
+		So(cache.Len(), ShouldEqual, 500)
+	})
+}
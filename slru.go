@@ -0,0 +1,87 @@
+package stablecache
+
+import (
+	"container/list"
+	"stablecache/basic"
+)
+
+// slru is the segmented LRU policy: new entries start in probation and a
+// hit promotes them to protected. Protected is bounded by ProtectedRatio,
+// when full its least recently used entry goes back to probation.
+// Victims come from probation first, so a scan only churns probation.
+type slru struct {
+	probation    *list.List
+	protected    *list.List
+	maxProtected int
+}
+
+func newSLRU(size uint64, o basic.Options) *slru {
+	return &slru{
+		probation:    list.New(),
+		protected:    list.New(),
+		maxProtected: ratio(size, o.ProtectedRatio, 0.8),
+	}
+}
+
+func (p *slru) list(seg int8) *list.List {
+	if seg == segProtected {
+		return p.protected
+	}
+	return p.probation
+}
+
+func (p *slru) add(h uintptr) *node {
+	n := &node{h: h, seg: segProbation}
+	n.e = p.probation.PushFront(n)
+	return n
+}
+
+func (p *slru) touch(n *node) bool {
+	return false
+}
+
+func (p *slru) hit(n *node) {
+	if n.e != nil {
+		p.promote(n)
+	}
+}
+
+// promote move n to the front of protected, demoting when it overflows
+func (p *slru) promote(n *node) {
+	move(n, p.list(n.seg), p.protected, segProtected)
+	if p.protected.Len() > p.maxProtected {
+		move(p.protected.Back().Value.(*node), p.protected, p.probation, segProbation)
+	}
+}
+
+func (p *slru) remove(n *node) {
+	if n.e != nil {
+		p.list(n.seg).Remove(n.e)
+		n.e = nil
+	}
+}
+
+// victim return the next entry to evict without removing it
+func (p *slru) victim() *node {
+	if e := p.probation.Back(); e != nil {
+		return e.Value.(*node)
+	}
+	if e := p.protected.Back(); e != nil {
+		return e.Value.(*node)
+	}
+	return nil
+}
+
+func (p *slru) evict() (uintptr, bool) {
+	n := p.victim()
+	if n == nil {
+		return 0, false
+	}
+	p.remove(n)
+	return n.h, true
+}
+
+func (p *slru) each(fn func(h uintptr)) {
+	walk(p.probation, fn)
+	walk(p.protected, fn)
+}
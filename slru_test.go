@@ -0,0 +1,35 @@
+package stablecache
+
+import (
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSLRU(t *testing.T) {
+	Convey("a hit promotes to protected and overflow demotes back", t, func() {
+		o := basic.NewOptions(basic.WithProtectedRatio(0.5))
+		p := newSLRU(4, o)
+		So(p.maxProtected, ShouldEqual, 2)
+		var nodes []*node
+		for h := uintptr(0); h < 4; h++ {
+			nodes = append(nodes, p.add(h))
+		}
+		p.hit(nodes[0])
+		p.hit(nodes[1])
+		p.hit(nodes[2])
+		So(p.protected.Len(), ShouldEqual, 2)
+		So(nodes[0].seg, ShouldEqual, segProbation)
+		h, _ := p.evict()
+		So(h, ShouldEqual, 3)
+		h, _ = p.evict()
+		So(h, ShouldEqual, 0)
+	})
+
+	Convey("slru keeps the hot set through a scan", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1), basic.WithType(SLRU))
+		So(scan(cache), ShouldBeGreaterThan, 45)
+	})
+}
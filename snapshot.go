@@ -0,0 +1,65 @@
+package stablecache
+
+import (
+	"io"
+	"stablecache/basic"
+)
+
+// WithCodec set the codec of Snapshot and Restore, gob by default
+func (c *LRUCache[K, V]) WithCodec(codec basic.Codec[K, V]) {
+	c.codec = codec
+}
+
+func (c *LRUCache[K, V]) getCodec() basic.Codec[K, V] {
+	if c.codec == nil {
+		return basic.GobCodec[K, V]{}
+	}
+	return c.codec
+}
+
+// Snapshot write every live entry to w. Shards are copied one at a time
+// under their read lock and encoded after it is released, so writers are
+// only held up for the copy of one shard. Within a shard entries go from
+// the first to be evicted to the last, Restore rebuilds the same order.
+// Resize waits for the snapshot to finish.
+func (c *LRUCache[K, V]) Snapshot(w io.Writer) error {
+	c.resizeMu.Lock()
+	defer c.resizeMu.Unlock()
+	enc := c.getCodec().NewEncoder(w)
+	t := c.table.Load()
+	var entries []basic.Entry[K, V]
+	for i := range t.buckets {
+		entries = t.buckets[i].entries(entries[:0])
+		if err := basic.EncodeEntries(enc, entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore load the entries of a snapshot, keeping their expiration and
+// order. Entries that expired in the meantime are skipped.
+func (c *LRUCache[K, V]) Restore(r io.Reader) error {
+	return basic.DecodeEntries(c.stamps.clock, c.getCodec().NewDecoder(r), func(e *basic.Entry[K, V]) {
+		h := ehash(e.Key)
+		c.table.Load().bucket(h).set(e.Key, e.Value, h, e.Expiration, e.Duration, e.Tags, nil)
+	})
+}
+
+// entries append the live entries of the bucket to dst in eviction order
+func (b *LRUBucket[K, V]) entries(dst []basic.Entry[K, V]) []basic.Entry[K, V] {
+	now := b.now()
+	b.mu.RLock()
+	epoch := b.stamps.epoch.Load()
+	if b.moved == nil {
+		b.policy.each(func(h uintptr) {
+			item := b.items[h]
+			e := basic.Entry[K, V]{Key: item.key, Value: item.obj, Expiration: item.expiration, Duration: item.duration, Tags: item.tags}
+			if item.ns == nil && !item.stale(epoch) && !e.Expired(now) {
+				dst = append(dst, e)
+			}
+		})
+	}
+	b.mu.RUnlock()
+	return dst
+}
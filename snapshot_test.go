@@ -0,0 +1,47 @@
+package stablecache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUSnapshot(t *testing.T) {
+	Convey("restore keeps the lru order and the ttl", t, func() {
+		cache := NewLRUCache[int, string](100, basic.WithShards(1))
+		for i := 0; i < 10; i++ {
+			cache.SetWithExp(i, "v", time.Duration(i+1)*time.Minute)
+		}
+		cache.Get(3)
+		cache.Set(10, "v")
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+
+		restored := NewLRUCache[int, string](100, basic.WithShards(1))
+		So(restored.Restore(&buf), ShouldBeNil)
+		var order []int
+		b := &restored.table.Load().buckets[0]
+		b.policy.each(func(h uintptr) { order = append(order, b.items[h].key) })
+		So(order, ShouldResemble, []int{0, 1, 2, 4, 5, 6, 7, 8, 9, 3, 10})
+		So(b.items[ehash(5)].duration, ShouldEqual, int64(6*time.Minute))
+	})
+
+	Convey("json snapshots work across shard counts", t, func() {
+		cache := NewLRUCache[string, int](100, basic.WithShards(4))
+		cache.WithCodec(basic.JSONCodec[string, int]{})
+		cache.Set("a", 1)
+		cache.Set("b", 2)
+		var buf bytes.Buffer
+		So(cache.Snapshot(&buf), ShouldBeNil)
+		restored := NewLRUCache[string, int](100, basic.WithShards(16))
+		restored.WithCodec(basic.JSONCodec[string, int]{})
+		So(restored.Restore(&buf), ShouldBeNil)
+		v, err := restored.Get("b")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 2)
+	})
+}
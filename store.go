@@ -0,0 +1,107 @@
+package stablecache
+
+import (
+	"context"
+	"stablecache/basic"
+	"sync"
+	"time"
+)
+
+// Store is a shared cache tier behind the in-process caches, such as a
+// Redis-like server. Get returns NotFound on a miss; MGet leaves missing
+// keys out of its result. A ttl of 0 never expires.
+type Store[K comparable, V any] interface {
+	Get(ctx context.Context, k K) (V, error)
+	Set(ctx context.Context, k K, v V, ttl time.Duration) error
+	Delete(ctx context.Context, k K) error
+	MGet(ctx context.Context, ks []K) (map[K]V, error)
+}
+
+type memoryItem[V any] struct {
+	obj        V
+	expiration int64
+}
+
+// MemoryStore is an in-memory Store for tests
+type MemoryStore[K comparable, V any] struct {
+	noCopy
+	mu    sync.RWMutex
+	items map[K]memoryItem[V]
+	clock basic.Clock
+}
+
+// NewMemoryStore new empty store. Of opts only basic.WithClock applies,
+// it tells the time of the ttls.
+func NewMemoryStore[K comparable, V any](opts ...basic.Option) *MemoryStore[K, V] {
+	o := basic.NewOptions(opts...)
+	return &MemoryStore[K, V]{items: make(map[K]memoryItem[V]), clock: o.Clock}
+}
+
+func (s *MemoryStore[K, V]) get(k K, now int64) (V, bool) {
+	item, ok := s.items[k]
+	if !ok || (item.expiration != 0 && item.expiration <= now) {
+		var zero V
+		return zero, false
+	}
+	return item.obj, true
+}
+
+func (s *MemoryStore[K, V]) Get(ctx context.Context, k K) (r V, err error) {
+	if err := ctx.Err(); err != nil {
+		return r, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.get(k, s.clock.Now())
+	if !ok {
+		return r, NotFound
+	}
+	return r, nil
+}
+
+func (s *MemoryStore[K, V]) Set(ctx context.Context, k K, v V, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	item := memoryItem[V]{obj: v}
+	if ttl > 0 {
+		item.expiration = s.clock.Now() + int64(ttl)
+	}
+	s.mu.Lock()
+	s.items[k] = item
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore[K, V]) Delete(ctx context.Context, k K) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.items, k)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore[K, V]) MGet(ctx context.Context, ks []K) (map[K]V, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	r := make(map[K]V, len(ks))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range ks {
+		if v, ok := s.get(k, now); ok {
+			r[k] = v
+		}
+	}
+	return r, nil
+}
+
+// Len return the number of entries, expired ones included
+func (s *MemoryStore[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
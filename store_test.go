@@ -0,0 +1,39 @@
+package stablecache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryStore(t *testing.T) {
+	Convey("memory store honors ttl and context", t, func() {
+		ctx := context.Background()
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		s := NewMemoryStore[string, int](basic.WithClock(clock))
+		So(s.Set(ctx, "a", 1, 0), ShouldBeNil)
+		So(s.Set(ctx, "b", 2, time.Second), ShouldBeNil)
+		_, err := s.Get(ctx, "b")
+		So(err, ShouldBeNil)
+		clock.Advance(time.Second)
+		v, err := s.Get(ctx, "a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		_, err = s.Get(ctx, "b")
+		So(err, ShouldEqual, NotFound)
+		m, err := s.MGet(ctx, []string{"a", "b", "c"})
+		So(err, ShouldBeNil)
+		So(m, ShouldResemble, map[string]int{"a": 1})
+		So(s.Delete(ctx, "a"), ShouldBeNil)
+		So(s.Len(), ShouldEqual, 1)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = s.Get(cancelled, "a")
+		So(err, ShouldEqual, context.Canceled)
+	})
+}
@@ -0,0 +1,58 @@
+package stablecache
+
+import (
+	"stablecache/basic"
+	"time"
+)
+
+// SetWithTags set v with a ttl of dur and tag it for InvalidateTag. The
+// tags replace those of an earlier set; a plain Set drops them.
+func (c *LRUCache[K, V]) SetWithTags(k K, v V, dur time.Duration, tags ...string) {
+	hash := ehash(k)
+	b := c.table.Load().bucket(hash)
+	b.set(k, v, hash, b.now()+int64(dur), int64(dur), tags, c.wal.Load())
+}
+
+// InvalidateTag delete every entry tagged with tag and return how many
+func (c *LRUCache[K, V]) InvalidateTag(tag string) int {
+	n := 0
+	for _, k := range c.idx.Tag(tag) {
+		h := ehash(k)
+		if c.table.Load().bucket(h).delete(k, h, nil, basic.OpDelete, func(i LRUItem[K, V]) bool { return basic.HasTag(i.tags, tag) }, c.wal.Load()) {
+			n++
+		}
+	}
+	return n
+}
+
+// InvalidatePrefix delete every string key starting with p and return how
+// many. Without basic.WithPrefixIndex it walks every shard.
+func (c *LRUCache[K, V]) InvalidatePrefix(p string) int {
+	ks, ok := c.idx.Prefix(p)
+	if !ok {
+		t := c.table.Load()
+		for i := range t.buckets {
+			ks = t.buckets[i].prefixed(ks, p)
+		}
+	}
+	n := 0
+	for _, k := range ks {
+		h := ehash(k)
+		if c.table.Load().bucket(h).delete(k, h, nil, basic.OpDelete, nil, c.wal.Load()) {
+			n++
+		}
+	}
+	return n
+}
+
+// prefixed append the keys of the bucket starting with p to dst
+func (b *LRUBucket[K, V]) prefixed(dst []K, p string) []K {
+	b.mu.RLock()
+	for _, item := range b.items {
+		if item.ns == nil && basic.HasPrefix(item.key, p) {
+			dst = append(dst, item.key)
+		}
+	}
+	b.mu.RUnlock()
+	return dst
+}
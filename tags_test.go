@@ -0,0 +1,53 @@
+package stablecache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUTags(t *testing.T) {
+	Convey("lru invalidates by tag and prefix across shards", t, func() {
+		cache := NewLRUCache[string, int](1000, basic.WithShards(8), basic.WithPrefixIndex())
+		for i := 0; i < 30; i++ {
+			cache.SetWithTags(fmt.Sprintf("t1:%d", i), i, time.Minute, "t1")
+			cache.Set(fmt.Sprintf("t2:%d", i), i)
+		}
+		So(cache.InvalidateTag("t1"), ShouldEqual, 30)
+		_, err := cache.Get("t1:3")
+		So(err, ShouldEqual, NotFound)
+		So(cache.InvalidatePrefix("t2:1"), ShouldEqual, 11)
+		_, err = cache.Get("t2:2")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("entries the policy evicts leave the index", t, func() {
+		cache := NewLRUCache[int, int](10, basic.WithShards(1))
+		for i := 0; i < 100; i++ {
+			cache.SetWithTags(i, i, time.Minute, "t")
+		}
+		So(len(cache.idx.Tag("t")), ShouldEqual, len(cache.table.Load().buckets[0].items))
+	})
+	Convey("tags survive a restart on the log", t, func() {
+		dir := t.TempDir()
+		w, err := basic.OpenWAL[string, int](dir, nil)
+		So(err, ShouldBeNil)
+		cache := NewLRUCache[string, int](100)
+		So(cache.WithWAL(w), ShouldBeNil)
+		cache.SetWithTags("a0", 0, time.Minute, "tenant:a")
+		So(w.Compact(), ShouldBeNil)
+		cache.SetWithTags("a1", 1, time.Minute, "tenant:a")
+		cache.SetWithTags("b0", 0, time.Minute, "tenant:b")
+		So(w.Close(), ShouldBeNil)
+		w, _ = basic.OpenWAL[string, int](dir, nil)
+		defer w.Close()
+		restarted := NewLRUCache[string, int](100)
+		So(restarted.WithWAL(w), ShouldBeNil)
+		So(restarted.InvalidateTag("tenant:a"), ShouldEqual, 2)
+		So(restarted.Keys(), ShouldResemble, []string{"b0"})
+	})
+}
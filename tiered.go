@@ -0,0 +1,154 @@
+package stablecache
+
+import (
+	"context"
+	"stablecache/basic"
+	"time"
+)
+
+// Local is the in-process tier of Tiered, PartitionCache and LRUCache
+// are ones. It must not have a callback: Tiered does the loading.
+type Local[K comparable, V any] interface {
+	Get(K) (V, error)
+	SetWithExp(K, V, time.Duration)
+	Delete(K)
+}
+
+// Tiered is an in-process cache (L1) in front of a shared Store (L2).
+// A miss in L1 falls through to L2 and then to the loader; what L2 or the
+// loader returns is put in the tiers above it, each with its own ttl.
+type Tiered[K comparable, V any] struct {
+	noCopy
+	l1     Local[K, V]
+	l2     Store[K, V]
+	l1TTL  time.Duration
+	l2TTL  time.Duration
+	loader func(context.Context, K) (V, error)
+}
+
+// NewTiered new two tier cache, the ttls are 10s in L1 and 1m in L2
+func NewTiered[K comparable, V any](l1 Local[K, V], l2 Store[K, V]) *Tiered[K, V] {
+	return &Tiered[K, V]{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: 10 * time.Second,
+		l2TTL: time.Minute,
+	}
+}
+
+// WithLoader set the loader called when both tiers miss
+func (t *Tiered[K, V]) WithLoader(call func(context.Context, K) (V, error)) {
+	t.loader = call
+}
+
+// WithTTL set the ttl of the entries put in each tier
+func (t *Tiered[K, V]) WithTTL(l1, l2 time.Duration) {
+	t.l1TTL = l1
+	t.l2TTL = l2
+}
+
+// stale report whether err is the Timeout of an expired L1 entry, whose
+// value is still worth returning when nothing better can be had
+func stale(err error) bool {
+	return err == Timeout || err == basic.Timeout
+}
+
+// Get look k up in L1, then L2, then the loader.
+// error maybe not found, timeout with the expired L1 value when the lower
+// tiers fail, or the error of the loader, or of L2 when there is no loader
+func (t *Tiered[K, V]) Get(ctx context.Context, k K) (r V, err error) {
+	old, err := t.l1.Get(k)
+	if err == nil {
+		return old, nil
+	}
+	expired := stale(err)
+	v, err := t.l2.Get(ctx, k)
+	if err == nil {
+		t.l1.SetWithExp(k, v, t.l1TTL)
+		return v, nil
+	}
+	if t.loader != nil {
+		v, err = t.load(ctx, k)
+		if err == nil {
+			return v, nil
+		}
+	}
+	if expired {
+		return old, Timeout
+	}
+	if err == NotFound || err == basic.NotFound {
+		return r, NotFound
+	}
+	return r, err
+}
+
+// GetMany look every key of ks up, L2 is asked for all the L1 misses in
+// one MGet. Keys nobody has are left out of the result; the error is the
+// first one of L2 or the loader.
+func (t *Tiered[K, V]) GetMany(ctx context.Context, ks []K) (map[K]V, error) {
+	r := make(map[K]V, len(ks))
+	var misses []K
+	for _, k := range ks {
+		if v, err := t.l1.Get(k); err == nil {
+			r[k] = v
+		} else {
+			misses = append(misses, k)
+		}
+	}
+	if len(misses) == 0 {
+		return r, nil
+	}
+	found, err := t.l2.MGet(ctx, misses)
+	var first error
+	if err != nil {
+		first = err
+	}
+	for _, k := range misses {
+		if v, ok := found[k]; ok {
+			t.l1.SetWithExp(k, v, t.l1TTL)
+			r[k] = v
+			continue
+		}
+		if t.loader == nil {
+			continue
+		}
+		v, err := t.load(ctx, k)
+		if err == nil {
+			r[k] = v
+		} else if err != NotFound && first == nil {
+			first = err
+		}
+	}
+	return r, first
+}
+
+// load call the loader and fill both tiers. Failing to fill L2 is not an
+// error: the value is good, only the next process has to load it again.
+func (t *Tiered[K, V]) load(ctx context.Context, k K) (V, error) {
+	v, err := t.loader(ctx, k)
+	if err != nil {
+		return v, err
+	}
+	t.l2.Set(ctx, k, v, t.l2TTL)
+	t.l1.SetWithExp(k, v, t.l1TTL)
+	return v, nil
+}
+
+// Set write v to L2, then to L1
+func (t *Tiered[K, V]) Set(ctx context.Context, k K, v V) error {
+	if err := t.l2.Set(ctx, k, v, t.l2TTL); err != nil {
+		return err
+	}
+	t.l1.SetWithExp(k, v, t.l1TTL)
+	return nil
+}
+
+// Delete remove k from L2, then from L1. Other processes keep their L1
+// copy until its ttl runs out.
+func (t *Tiered[K, V]) Delete(ctx context.Context, k K) error {
+	if err := t.l2.Delete(ctx, k); err != nil {
+		return err
+	}
+	t.l1.Delete(k)
+	return nil
+}
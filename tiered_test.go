@@ -0,0 +1,128 @@
+package stablecache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// countingStore count the calls that reach L2
+type countingStore[K comparable, V any] struct {
+	*MemoryStore[K, V]
+	gets, mgets int
+	fail        error
+}
+
+func (s *countingStore[K, V]) Get(ctx context.Context, k K) (V, error) {
+	s.gets++
+	if s.fail != nil {
+		var zero V
+		return zero, s.fail
+	}
+	return s.MemoryStore.Get(ctx, k)
+}
+
+func (s *countingStore[K, V]) MGet(ctx context.Context, ks []K) (map[K]V, error) {
+	s.mgets++
+	return s.MemoryStore.MGet(ctx, ks)
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("a miss falls through both tiers and fills them", t, func() {
+		l2 := &countingStore[string, int]{MemoryStore: NewMemoryStore[string, int]()}
+		l1 := basic.NewPartitionCache[string, int]()
+		defer l1.Close()
+		tc := NewTiered[string, int](l1, l2)
+		loads := 0
+		tc.WithLoader(func(ctx context.Context, k string) (int, error) {
+			loads++
+			return len(k), nil
+		})
+		v, err := tc.Get(ctx, "abc")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 3)
+		So(loads, ShouldEqual, 1)
+		v, err = l2.MemoryStore.Get(ctx, "abc")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 3)
+
+		v, err = tc.Get(ctx, "abc")
+		So(err, ShouldBeNil)
+		So(l2.gets, ShouldEqual, 1)
+		So(loads, ShouldEqual, 1)
+	})
+
+	Convey("an L2 hit fills L1 with its own ttl", t, func() {
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		l2 := NewMemoryStore[string, int](basic.WithClock(clock))
+		l1 := NewLRUCache[string, int](100, basic.WithClock(clock))
+		defer l1.Close()
+		tc := NewTiered[string, int](l1, l2)
+		tc.WithTTL(time.Second, time.Hour)
+		l2.Set(ctx, "a", 1, 0)
+		v, err := tc.Get(ctx, "a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		clock.Advance(time.Second + 1)
+		_, err = l1.Get("a")
+		So(err, ShouldEqual, Timeout)
+		v, err = tc.Get(ctx, "a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+	})
+
+	Convey("an expired L1 value is returned with timeout when L2 fails", t, func() {
+		clock := basic.NewFakeClock(time.Unix(1000, 0))
+		l2 := &countingStore[string, int]{MemoryStore: NewMemoryStore[string, int](basic.WithClock(clock))}
+		l1 := basic.NewPartitionCache[string, int](basic.WithClock(clock))
+		defer l1.Close()
+		tc := NewTiered[string, int](l1, l2)
+		tc.WithTTL(time.Second, time.Hour)
+		So(tc.Set(ctx, "a", 1), ShouldBeNil)
+		clock.Advance(time.Second + 1)
+		l2.fail = errors.New("down")
+		v, err := tc.Get(ctx, "a")
+		So(err, ShouldEqual, Timeout)
+		So(v, ShouldEqual, 1)
+		_, err = tc.Get(ctx, "b")
+		So(err, ShouldEqual, l2.fail)
+	})
+
+	Convey("get many asks L2 once for every L1 miss", t, func() {
+		l2 := &countingStore[int, int]{MemoryStore: NewMemoryStore[int, int]()}
+		l1 := basic.NewPartitionCache[int, int]()
+		defer l1.Close()
+		tc := NewTiered[int, int](l1, l2)
+		tc.WithLoader(func(ctx context.Context, k int) (int, error) {
+			if k == 9 {
+				return 0, NotFound
+			}
+			return k * 10, nil
+		})
+		tc.Set(ctx, 1, 10)
+		l2.MemoryStore.Set(ctx, 2, 20, 0)
+		r, err := tc.GetMany(ctx, []int{1, 2, 3, 9})
+		So(err, ShouldBeNil)
+		So(r, ShouldResemble, map[int]int{1: 10, 2: 20, 3: 30})
+		So(l2.mgets, ShouldEqual, 1)
+		So(l2.gets, ShouldEqual, 0)
+	})
+
+	Convey("delete clears both tiers", t, func() {
+		l2 := NewMemoryStore[string, int]()
+		l1 := basic.NewPartitionCache[string, int]()
+		defer l1.Close()
+		tc := NewTiered[string, int](l1, l2)
+		tc.Set(ctx, "a", 1)
+		So(tc.Delete(ctx, "a"), ShouldBeNil)
+		_, err := tc.Get(ctx, "a")
+		So(err, ShouldEqual, NotFound)
+	})
+}
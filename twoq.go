@@ -0,0 +1,80 @@
+package stablecache
+
+import (
+	"container/list"
+	"stablecache/basic"
+)
+
+// twoQ is the 2Q policy. New entries go into the A1in FIFO and a hit there
+// does nothing. Entries leaving A1in are remembered by hash in the A1out
+// ghost FIFO, a key coming back while in A1out was hot and goes into Am,
+// an LRU. A scan only ever passes through A1in.
+type twoQ struct {
+	in    *list.List
+	am    *list.List
+	out   *ghost
+	maxIn int
+}
+
+func newTwoQ(size uint64, o basic.Options) *twoQ {
+	return &twoQ{
+		in:    list.New(),
+		am:    list.New(),
+		out:   newGhost(int(size / 2)),
+		maxIn: ratio(size, o.WindowRatio, 0.25),
+	}
+}
+
+func (p *twoQ) list(seg int8) *list.List {
+	if seg == segMain {
+		return p.am
+	}
+	return p.in
+}
+
+func (p *twoQ) add(h uintptr) *node {
+	n := &node{h: h, seg: segSmall}
+	if p.out.take(h) {
+		n.seg = segMain
+	}
+	n.e = p.list(n.seg).PushFront(n)
+	return n
+}
+
+func (p *twoQ) touch(n *node) bool {
+	return false
+}
+
+func (p *twoQ) hit(n *node) {
+	if n.e != nil && n.seg == segMain {
+		p.am.MoveToFront(n.e)
+	}
+}
+
+func (p *twoQ) remove(n *node) {
+	if n.e != nil {
+		p.list(n.seg).Remove(n.e)
+		n.e = nil
+	}
+}
+
+func (p *twoQ) evict() (uintptr, bool) {
+	if p.in.Len() > p.maxIn || p.am.Len() == 0 {
+		e := p.in.Back()
+		if e == nil {
+			return 0, false
+		}
+		n := e.Value.(*node)
+		p.remove(n)
+		p.out.add(n.h)
+		return n.h, true
+	}
+	n := p.am.Back().Value.(*node)
+	p.remove(n)
+	return n.h, true
+}
+
+func (p *twoQ) each(fn func(h uintptr)) {
+	walk(p.in, fn)
+	walk(p.am, fn)
+}
@@ -0,0 +1,52 @@
+package stablecache
+
+import (
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTwoQ(t *testing.T) {
+	Convey("keys coming back from A1out go to Am", t, func() {
+		p := newTwoQ(8, basic.Options{})
+		So(p.maxIn, ShouldEqual, 2)
+		a := p.add(1)
+		p.hit(a)
+		So(a.seg, ShouldEqual, segSmall)
+		p.add(2)
+		p.add(3)
+		h, _ := p.evict()
+		So(h, ShouldEqual, 1)
+		So(p.out.keys, ShouldContainKey, uintptr(1))
+		a = p.add(1)
+		So(a.seg, ShouldEqual, segMain)
+		So(p.out.keys, ShouldNotContainKey, uintptr(1))
+		// A1in is within its share now, Am gives up its LRU entry
+		h, _ = p.evict()
+		So(h, ShouldEqual, 1)
+		So(p.out.keys, ShouldNotContainKey, uintptr(1))
+	})
+
+	Convey("2q keeps a working set in Am through a scan", t, func() {
+		cache := NewLRUCache[int, int](100, basic.WithShards(1), basic.WithType(TwoQ))
+		get := func(i int) {
+			if _, err := cache.Get(i); err != nil {
+				cache.Set(i, i)
+			}
+		}
+		// the working set cycles through A1in and A1out into Am
+		cold := 10000
+		for round := 0; round < 20; round++ {
+			for i := 0; i < 50; i++ {
+				get(i)
+				if i%2 == 0 {
+					cache.Set(cold, cold)
+					cold++
+				}
+			}
+		}
+		So(scan(cache), ShouldBeGreaterThan, 45)
+	})
+}
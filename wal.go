@@ -0,0 +1,28 @@
+package stablecache
+
+import "stablecache/basic"
+
+// WithWAL replay the snapshot and the log of w into the cache, then log
+// every set, delete and expiry to it. Call it before the cache is used;
+// snapshots of the log are written with the codec of WithCodec and keep
+// the eviction order.
+func (c *LRUCache[K, V]) WithWAL(w *basic.WAL[K, V]) error {
+	err := w.Attach(c.stamps.clock, c.Snapshot, c.Restore, func(op basic.Op, e *basic.Entry[K, V]) {
+		if op == basic.OpClear {
+			c.InvalidateAll()
+			return
+		}
+		h := ehash(e.Key)
+		b := c.table.Load().bucket(h)
+		if op == basic.OpSet {
+			b.set(e.Key, e.Value, h, e.Expiration, e.Duration, e.Tags, nil)
+		} else {
+			b.delete(e.Key, h, nil, op, nil, nil)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	c.wal.Store(w)
+	return nil
+}
@@ -0,0 +1,72 @@
+package stablecache
+
+import (
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUWAL(t *testing.T) {
+	Convey("a restart replays the log on top of the snapshot in lru order", t, func() {
+		dir := t.TempDir()
+		w, err := basic.OpenWAL[int, string](dir, nil)
+		So(err, ShouldBeNil)
+		cache := NewLRUCache[int, string](100, basic.WithShards(1))
+		So(cache.WithWAL(w), ShouldBeNil)
+		for i := 0; i < 5; i++ {
+			cache.Set(i, "v")
+		}
+		So(w.Compact(), ShouldBeNil)
+		cache.Get(1)
+		cache.Set(5, "v")
+		cache.Delete(2)
+		cache.SetWithExp(6, "gone", time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		cache.deleteExpired()
+		So(w.Close(), ShouldBeNil)
+
+		w, _ = basic.OpenWAL[int, string](dir, nil)
+		restored := NewLRUCache[int, string](100, basic.WithShards(1))
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		var order []int
+		b := &restored.table.Load().buckets[0]
+		b.policy.each(func(h uintptr) { order = append(order, b.items[h].key) })
+		// the hit on 1 was not logged, the snapshot order is kept
+		So(order, ShouldResemble, []int{0, 1, 3, 4, 5})
+	})
+}
+
+func TestLRUWALAdmission(t *testing.T) {
+	Convey("an entry rejected by admission is not logged", t, func() {
+		dir := t.TempDir()
+		w, err := basic.OpenWAL[int, string](dir, nil)
+		So(err, ShouldBeNil)
+		cache := NewLRUCache[int, string](3, basic.WithShards(1), basic.WithAdmission())
+		So(cache.WithWAL(w), ShouldBeNil)
+		for i := 0; i < 4; i++ {
+			cache.Set(i, "hot")
+		}
+		for round := 0; round < 10; round++ {
+			for i := 0; i < 4; i++ {
+				cache.Get(i)
+			}
+		}
+		cache.Set(99, "cold")
+		_, err = cache.Peek(99)
+		So(err, ShouldEqual, NotFound)
+		So(w.Close(), ShouldBeNil)
+
+		w, _ = basic.OpenWAL[int, string](dir, nil)
+		// without admission a logged 99 would be replayed in
+		restored := NewLRUCache[int, string](3, basic.WithShards(1))
+		So(restored.WithWAL(w), ShouldBeNil)
+		defer w.Close()
+		_, err = restored.Peek(99)
+		So(err, ShouldEqual, NotFound)
+		So(restored.Len(), ShouldEqual, 4)
+	})
+}
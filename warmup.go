@@ -0,0 +1,14 @@
+package stablecache
+
+import (
+	"context"
+	"stablecache/basic"
+)
+
+// Warm load ks with the callback, see basic.Warmup
+func (c *LRUCache[K, V]) Warm(ctx context.Context, ks []K, opts ...basic.WarmupOption) (basic.WarmupReport[K], error) {
+	if c.caller == nil {
+		return basic.WarmupReport[K]{}, basic.NoCallback
+	}
+	return basic.Warmup(ctx, ks, c.caller, c.SetWithExp, c.defaultDuration, opts...)
+}
@@ -0,0 +1,23 @@
+package stablecache
+
+import (
+	"context"
+	"testing"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLRUWarm(t *testing.T) {
+	Convey("lru warm fills the cache", t, func() {
+		cache := NewLRUCache[int, int](100)
+		cache.WithCallback(func(k int) (int, error) { return k * 2, nil })
+		r, err := cache.Warm(context.Background(), []int{1, 2, 3}, basic.WithConcurrency(2))
+		So(err, ShouldBeNil)
+		So(r.Done, ShouldEqual, 3)
+		v, err := cache.Get(3)
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 6)
+	})
+}
@@ -0,0 +1,324 @@
+package stablecache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Writer is the backing store behind a WriteCache. Write-behind hands it
+// whole batches, write-through a single key at a time.
+type Writer[K comparable, V any] interface {
+	Write(ctx context.Context, entries map[K]V) error
+	Delete(ctx context.Context, ks []K) error
+}
+
+// WriteBehindOptions holds the settings of write-behind
+type WriteBehindOptions struct {
+	// Interval is how often pending writes are flushed
+	Interval time.Duration
+	// BatchSize is the number of pending keys that triggers a flush
+	// before the interval is up, and the size of one Writer call
+	BatchSize int
+	// Retries is how many times a failed batch is tried again in a flush
+	Retries int
+	// Backoff is the wait before the first retry, it doubles every retry
+	Backoff time.Duration
+}
+
+// WriteBehindOption configures write-behind
+type WriteBehindOption func(*WriteBehindOptions)
+
+// WithFlushInterval set how often pending writes are flushed, 1s by
+// default and when d is not positive
+func WithFlushInterval(d time.Duration) WriteBehindOption {
+	return func(o *WriteBehindOptions) {
+		o.Interval = d
+	}
+}
+
+// WithBatchSize set the flush threshold and batch size, 100 by default
+func WithBatchSize(n int) WriteBehindOption {
+	return func(o *WriteBehindOptions) {
+		o.BatchSize = n
+	}
+}
+
+// WithRetries set how many times a failed batch is retried, 3 by default
+func WithRetries(n int) WriteBehindOption {
+	return func(o *WriteBehindOptions) {
+		o.Retries = n
+	}
+}
+
+// WithBackoff set the wait before the first retry, 100ms by default
+func WithBackoff(d time.Duration) WriteBehindOption {
+	return func(o *WriteBehindOptions) {
+		o.Backoff = d
+	}
+}
+
+// pendingWrite is the last write of a key that is not flushed yet
+type pendingWrite[V any] struct {
+	obj V
+	del bool
+}
+
+// WriteCache is an in-process cache whose writes also go to a Writer,
+// either synchronously (write-through) or coalesced per key and flushed
+// in batches (write-behind).
+type WriteCache[K comparable, V any] struct {
+	noCopy
+	defaultDuration time.Duration
+	l               Local[K, V]
+	w               Writer[K, V]
+	behind          bool
+	o               WriteBehindOptions
+
+	mu      sync.Mutex
+	pending map[K]pendingWrite[V]
+	err     error
+	closed  bool
+
+	flushMu sync.Mutex
+	kick    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewWriteThrough new cache writing every Set and Delete to w before
+// the cache itself
+func NewWriteThrough[K comparable, V any](l Local[K, V], w Writer[K, V]) *WriteCache[K, V] {
+	return &WriteCache[K, V]{
+		defaultDuration: 10 * time.Second,
+		l:               l,
+		w:               w,
+	}
+}
+
+// NewWriteBehind new cache writing to w in the background. Only the last
+// write of a key is kept until it is flushed; Close flushes what is left.
+func NewWriteBehind[K comparable, V any](l Local[K, V], w Writer[K, V], opts ...WriteBehindOption) *WriteCache[K, V] {
+	o := WriteBehindOptions{
+		Interval:  time.Second,
+		BatchSize: 100,
+		Retries:   3,
+		Backoff:   100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	c := &WriteCache[K, V]{
+		defaultDuration: 10 * time.Second,
+		l:               l,
+		w:               w,
+		behind:          true,
+		o:               o,
+		pending:         make(map[K]pendingWrite[V]),
+		kick:            make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Get read k from the cache, falling back to a write that is not flushed
+// yet when the cache evicted it
+func (c *WriteCache[K, V]) Get(k K) (r V, err error) {
+	r, err = c.l.Get(k)
+	if err == nil || !c.behind {
+		return r, err
+	}
+	c.mu.Lock()
+	p, ok := c.pending[k]
+	c.mu.Unlock()
+	if ok && !p.del {
+		return p.obj, nil
+	}
+	return r, err
+}
+
+func (c *WriteCache[K, V]) Set(ctx context.Context, k K, v V) error {
+	return c.SetWithExp(ctx, k, v, c.defaultDuration)
+}
+
+// SetWithExp write v to the cache with a ttl of dur, and to the Writer.
+// In write-through mode the cache is only updated when the Writer succeeds.
+func (c *WriteCache[K, V]) SetWithExp(ctx context.Context, k K, v V, dur time.Duration) error {
+	if !c.behind {
+		if err := c.w.Write(ctx, map[K]V{k: v}); err != nil {
+			return err
+		}
+		c.l.SetWithExp(k, v, dur)
+		return nil
+	}
+	c.l.SetWithExp(k, v, dur)
+	return c.queue(k, pendingWrite[V]{obj: v})
+}
+
+// Delete remove k from the Writer and the cache
+func (c *WriteCache[K, V]) Delete(ctx context.Context, k K) error {
+	if !c.behind {
+		if err := c.w.Delete(ctx, []K{k}); err != nil {
+			return err
+		}
+		c.l.Delete(k)
+		return nil
+	}
+	c.l.Delete(k)
+	return c.queue(k, pendingWrite[V]{del: true})
+}
+
+func (c *WriteCache[K, V]) queue(k K, p pendingWrite[V]) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return Closed
+	}
+	c.pending[k] = p
+	n := len(c.pending)
+	c.mu.Unlock()
+	if n >= c.o.BatchSize {
+		select {
+		case c.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Pending return the number of keys waiting for a flush
+func (c *WriteCache[K, V]) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Err return the last error of a background flush, if any
+func (c *WriteCache[K, V]) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Flush write every pending key now. Keys of a batch that still fails
+// after the retries stay pending, unless they were written again meanwhile.
+func (c *WriteCache[K, V]) Flush(ctx context.Context) error {
+	if !c.behind {
+		return nil
+	}
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = make(map[K]pendingWrite[V])
+	c.mu.Unlock()
+
+	var first error
+	sets := make(map[K]V)
+	var dels []K
+	flush := func(last bool) {
+		if len(sets) > 0 && (last || len(sets) >= c.o.BatchSize) {
+			if err := c.retry(ctx, func(ctx context.Context) error { return c.w.Write(ctx, sets) }); err != nil {
+				first = firstErr(first, err)
+				for k := range sets {
+					c.requeue(k, batch[k])
+				}
+			}
+			sets = make(map[K]V)
+		}
+		if len(dels) > 0 && (last || len(dels) >= c.o.BatchSize) {
+			if err := c.retry(ctx, func(ctx context.Context) error { return c.w.Delete(ctx, dels) }); err != nil {
+				first = firstErr(first, err)
+				for _, k := range dels {
+					c.requeue(k, batch[k])
+				}
+			}
+			dels = nil
+		}
+	}
+	for k, p := range batch {
+		if p.del {
+			dels = append(dels, k)
+		} else {
+			sets[k] = p.obj
+		}
+		flush(false)
+	}
+	flush(true)
+	return first
+}
+
+// Close stop the background flush and flush what is left. The error is
+// the one of that last flush: the keys it reports were not written.
+func (c *WriteCache[K, V]) Close() error {
+	if !c.behind {
+		return nil
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return Closed
+	}
+	c.closed = true
+	c.mu.Unlock()
+	close(c.stop)
+	<-c.done
+	return c.Flush(context.Background())
+}
+
+func (c *WriteCache[K, V]) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.o.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.kick:
+		case <-c.stop:
+			return
+		}
+		if err := c.Flush(context.Background()); err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+		}
+	}
+}
+
+// retry call fn until it succeeds, the retries run out or ctx is done
+func (c *WriteCache[K, V]) retry(ctx context.Context, fn func(context.Context) error) error {
+	backoff := c.o.Backoff
+	for i := 0; ; i++ {
+		err := fn(ctx)
+		if err == nil || i >= c.o.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// requeue put back a write that failed, unless k was written again
+func (c *WriteCache[K, V]) requeue(k K, p pendingWrite[V]) {
+	c.mu.Lock()
+	if _, ok := c.pending[k]; !ok {
+		c.pending[k] = p
+	}
+	c.mu.Unlock()
+}
+
+func firstErr(first, err error) error {
+	if first != nil {
+		return first
+	}
+	return err
+}
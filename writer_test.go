@@ -0,0 +1,162 @@
+package stablecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"stablecache/basic"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// recordWriter keep what was written and count the calls
+type recordWriter struct {
+	mu     sync.Mutex
+	data   map[string]int
+	writes int
+	fails  int
+}
+
+func newRecordWriter() *recordWriter {
+	return &recordWriter{data: make(map[string]int)}
+}
+
+func (w *recordWriter) Write(ctx context.Context, entries map[string]int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	if w.fails > 0 {
+		w.fails--
+		return errors.New("store down")
+	}
+	for k, v := range entries {
+		w.data[k] = v
+	}
+	return nil
+}
+
+func (w *recordWriter) Delete(ctx context.Context, ks []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	for _, k := range ks {
+		delete(w.data, k)
+	}
+	return nil
+}
+
+func (w *recordWriter) get(k string) (int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v, ok := w.data[k]
+	return v, ok
+}
+
+func TestWriteCache(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("write-through only caches what the writer took", t, func() {
+		w := newRecordWriter()
+		l1 := basic.NewPartitionCache[string, int]()
+		defer l1.Close()
+		c := NewWriteThrough[string, int](l1, w)
+		So(c.Set(ctx, "a", 1), ShouldBeNil)
+		v, _ := w.get("a")
+		So(v, ShouldEqual, 1)
+		w.fails = 1
+		So(c.Set(ctx, "b", 2), ShouldNotBeNil)
+		_, err := c.Get("b")
+		So(err, ShouldNotBeNil)
+		So(c.Delete(ctx, "a"), ShouldBeNil)
+		_, ok := w.get("a")
+		So(ok, ShouldBeFalse)
+		_, err = c.Get("a")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("write-behind coalesces writes of a key", t, func() {
+		w := newRecordWriter()
+		c := NewWriteBehind[string, int](NewLRUCache[string, int](100), w, WithFlushInterval(time.Hour))
+		for i := 0; i < 10; i++ {
+			So(c.Set(ctx, "counter", i), ShouldBeNil)
+		}
+		c.Set(ctx, "gone", 1)
+		c.Delete(ctx, "gone")
+		So(c.Pending(), ShouldEqual, 2)
+		_, ok := w.get("counter")
+		So(ok, ShouldBeFalse)
+		So(c.Flush(ctx), ShouldBeNil)
+		v, _ := w.get("counter")
+		So(v, ShouldEqual, 9)
+		So(w.writes, ShouldEqual, 2)
+		So(c.Close(), ShouldBeNil)
+		So(c.Set(ctx, "late", 1), ShouldEqual, Closed)
+	})
+
+	Convey("write-behind flushes once a batch is full", t, func() {
+		w := newRecordWriter()
+		c := NewWriteBehind[string, int](NewLRUCache[string, int](100), w, WithFlushInterval(time.Hour), WithBatchSize(4))
+		for _, k := range []string{"a", "b", "c", "d"} {
+			c.Set(ctx, k, 1)
+		}
+		So(func() bool {
+			for i := 0; i < 100; i++ {
+				if _, ok := w.get("d"); ok {
+					return true
+				}
+				time.Sleep(time.Millisecond)
+			}
+			return false
+		}(), ShouldBeTrue)
+		So(c.Close(), ShouldBeNil)
+	})
+
+	Convey("failed batches are retried and kept until they go through", t, func() {
+		w := newRecordWriter()
+		c := NewWriteBehind[string, int](NewLRUCache[string, int](100), w,
+			WithFlushInterval(time.Hour), WithRetries(1), WithBackoff(time.Millisecond))
+		c.Set(ctx, "a", 1)
+		w.fails = 1
+		So(c.Flush(ctx), ShouldBeNil)
+		So(w.writes, ShouldEqual, 2)
+
+		c.Set(ctx, "a", 2)
+		w.fails = 2
+		So(c.Flush(ctx), ShouldNotBeNil)
+		So(c.Pending(), ShouldEqual, 1)
+		v, err := c.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 2)
+		So(c.Close(), ShouldBeNil)
+		v, _ = w.get("a")
+		So(v, ShouldEqual, 2)
+	})
+
+	Convey("a flush interval that is not positive falls back to the default", t, func() {
+		for _, d := range []time.Duration{0, -time.Second} {
+			w := newRecordWriter()
+			c := NewWriteBehind[string, int](NewLRUCache[string, int](100), w, WithFlushInterval(d))
+			So(c.o.Interval, ShouldEqual, time.Second)
+			So(c.Set(ctx, "a", 1), ShouldBeNil)
+			So(c.Close(), ShouldBeNil)
+			v, _ := w.get("a")
+			So(v, ShouldEqual, 1)
+		}
+	})
+
+	Convey("a pending write is read back after the cache evicted it", t, func() {
+		w := newRecordWriter()
+		l := basic.NewPartitionCache[string, int]()
+		defer l.Close()
+		c := NewWriteBehind[string, int](l, w, WithFlushInterval(time.Hour))
+		c.Set(ctx, "a", 1)
+		l.Delete("a")
+		v, err := c.Get("a")
+		So(err, ShouldBeNil)
+		So(v, ShouldEqual, 1)
+		So(c.Close(), ShouldBeNil)
+	})
+}